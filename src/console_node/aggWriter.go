@@ -0,0 +1,321 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the buffered writer of the aggregation log
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policies when the aggregation queue is full
+const (
+	aggOverflowDrop  string = "drop"  // drop the new line and count it
+	aggOverflowBlock string = "block" // wait a short time for room, then drop
+)
+
+// Configuration of the aggregation writer
+var aggQueueSize int = 10000                   // lines held waiting to be written
+var aggOverflowPolicy string = aggOverflowDrop // what to do when the queue is full
+var aggBlockMs int = 100                       // time to wait for room with the block policy
+var aggFlushMs int = 1000                      // time between flushes of the buffered output
+var aggFsyncSecs int = 0                       // time between syncs to disk, 0 to leave it to the os
+var aggBufferSize int = 64 * 1024              // size of the output buffer
+
+// AggWriterStats - state of the aggregation writer
+type AggWriterStats struct {
+	QueueDepth    int    `json:"queue_depth"`
+	QueueSize     int    `json:"queue_size"`
+	Written       uint64 `json:"written"`
+	Dropped       uint64 `json:"dropped"`
+	WriteErrors   uint64 `json:"write_errors"`
+	LastFlushTime string `json:"last_flush_time,omitempty"`
+}
+
+// A line waiting for the aggregation log of a tenant, empty for the main log
+type aggLine struct {
+	tenant string
+	text   string
+}
+
+// An open aggregation log file
+type aggOutput struct {
+	f  *os.File
+	bw *bufio.Writer
+}
+
+// Globals for the aggregation writer
+var aggQueue chan aggLine = nil
+var aggRespin chan chan struct{} = nil
+var aggWriterOnce sync.Once
+var aggWritten, aggDropped, aggWriteErrors uint64
+var aggLastFlush atomic.Value // time.Duration
+
+// Read the aggregation writer configuration from the environment
+func initAggWriter() {
+	readSingleEnvVarInt("AGG_QUEUE_SIZE", &aggQueueSize, 100, 1000000)
+	readSingleEnvVarInt("AGG_BLOCK_MS", &aggBlockMs, 1, 10000)
+	readSingleEnvVarInt("AGG_FLUSH_MS", &aggFlushMs, 10, 60000)
+	readSingleEnvVarInt("AGG_FSYNC_SEC", &aggFsyncSecs, 0, 3600)
+	if val := os.Getenv("AGG_OVERFLOW_POLICY"); val != "" {
+		switch strings.ToLower(val) {
+		case aggOverflowDrop, aggOverflowBlock:
+			aggOverflowPolicy = strings.ToLower(val)
+		default:
+			log.Printf("Unknown AGG_OVERFLOW_POLICY %s, using %s", val, aggOverflowPolicy)
+		}
+	}
+	log.Printf("Aggregation writer: queue:%d, overflow:%s, flush:%dms, fsync:%ds",
+		aggQueueSize, aggOverflowPolicy, aggFlushMs, aggFsyncSecs)
+}
+
+// Start the goroutine that owns the aggregation log file
+func startAggWriter() {
+	aggWriterOnce.Do(func() {
+		aggQueue = make(chan aggLine, aggQueueSize)
+		aggRespin = make(chan chan struct{})
+		go runAggWriter()
+	})
+}
+
+// Queue a line to be written to the main aggregation log
+func writeToAggLog(str string) {
+	writeToTenantAggLog("", str)
+}
+
+// Queue a line to be written to the aggregation log of a tenant
+func writeToTenantAggLog(tenant, str string) {
+	// NOTE: the callers are the tail threads, they must never wait on the disk
+	if aggQueue == nil {
+		return
+	}
+	line := aggLine{tenant: tenant, text: str}
+	select {
+	case aggQueue <- line:
+		return
+	default:
+	}
+	if aggOverflowPolicy == aggOverflowBlock {
+		timer := time.NewTimer(time.Duration(aggBlockMs) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case aggQueue <- line:
+			return
+		case <-timer.C:
+		}
+	}
+	atomic.AddUint64(&aggDropped, 1)
+}
+
+// Function to close/open a new aggregation log file
+func respinAggLog() {
+	// when the file changes due to log rotation the writer must start a new one
+	startAggWriter()
+	done := make(chan struct{})
+	aggRespin <- done
+	<-done
+}
+
+// Name of the aggregation log of a tenant
+func tenantAggLogFile(tenant string) string {
+	if tenant == "" {
+		return conAggLogFile
+	}
+	return filepath.Join(filepath.Dir(conAggLogFile), "tenants", tenant, filepath.Base(conAggLogFile))
+}
+
+// Open an aggregation log file, truncating anything there if asked
+func openAggLog(fn string, truncate bool) *os.File {
+	// make sure the directory exists to put the file in place
+	pos := strings.LastIndex(fn, "/")
+	if pos < 0 {
+		log.Printf("Error: console log aggregation file name: %s", fn)
+		return nil
+	}
+	if _, err := ensureDirPresent(fn[:pos], logDirMode); err != nil {
+		log.Printf("Failed to respin aggregation file: %s", err)
+		return nil
+	}
+
+	log.Printf("Respinning aggregation log")
+	flags := os.O_APPEND | os.O_WRONLY | os.O_CREATE
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	calf, err := os.OpenFile(fn, flags, 0600)
+	if err != nil {
+		log.Printf("Could not open console aggregate log file: %s", err)
+		return nil
+	}
+	log.Printf("Restarted aggregation log file: %s", fn)
+	return calf
+}
+
+// Write the queued lines out to the aggregation logs
+func runAggWriter() {
+	// NOTE: the main log is only opened by a respin, the tenant logs on their first line
+	outputs := make(map[string]*aggOutput) // [tenant,*aggOutput]
+	started := make(map[string]bool)       // files started fresh by this process
+	var lastDropped uint64 = 0
+
+	flushTicker := time.NewTicker(time.Duration(aggFlushMs) * time.Millisecond)
+	defer flushTicker.Stop()
+	var syncC <-chan time.Time = nil
+	if aggFsyncSecs > 0 {
+		syncTicker := time.NewTicker(time.Duration(aggFsyncSecs) * time.Second)
+		defer syncTicker.Stop()
+		syncC = syncTicker.C
+	}
+
+	var writeLine func(tenant, str string)
+	open := func(tenant string) {
+		fn := tenantAggLogFile(tenant)
+		out := &aggOutput{f: openAggLog(fn, !started[fn])}
+		started[fn] = true
+		outputs[tenant] = out
+		if out.f != nil {
+			out.bw = bufio.NewWriterSize(out.f, aggBufferSize)
+			writeLine(tenant, "Starting aggregation log")
+		}
+	}
+	writeLine = func(tenant, str string) {
+		out, ok := outputs[tenant]
+		if !ok && tenant != "" {
+			open(tenant)
+			out = outputs[tenant]
+		}
+		if out == nil || out.bw == nil {
+			return
+		}
+		if _, err := out.bw.WriteString(str + "\n"); err != nil {
+			atomic.AddUint64(&aggWriteErrors, 1)
+			return
+		}
+		atomic.AddUint64(&aggWritten, 1)
+	}
+	flush := func() {
+		// make the gap in the log visible if anything was dropped
+		if dropped := atomic.LoadUint64(&aggDropped); dropped != lastDropped {
+			writeLine("", fmt.Sprintf("[console-node] aggregation queue full, dropped %d lines", dropped-lastDropped))
+			lastDropped = dropped
+		}
+		start := time.Now()
+		for _, out := range outputs {
+			if out.bw == nil {
+				continue
+			}
+			if err := out.bw.Flush(); err != nil {
+				atomic.AddUint64(&aggWriteErrors, 1)
+				log.Printf("Error writing aggregation log: %s", err)
+				out.bw.Reset(out.f)
+			}
+		}
+		aggLastFlush.Store(time.Since(start))
+	}
+
+	for {
+		select {
+		case line := <-aggQueue:
+			writeLine(line.tenant, line.text)
+			// take everything else that is waiting in one batch
+			for n := len(aggQueue); n > 0; n-- {
+				line = <-aggQueue
+				writeLine(line.tenant, line.text)
+			}
+			for _, out := range outputs {
+				if out.bw != nil && out.bw.Buffered() >= aggBufferSize/2 {
+					flush()
+					break
+				}
+			}
+		case <-flushTicker.C:
+			flush()
+		case <-syncC:
+			flush()
+			for _, out := range outputs {
+				if out.f != nil {
+					out.f.Sync()
+				}
+			}
+		case done := <-aggRespin:
+			// every log is reopened as any of them may have been rotated
+			flush()
+			tenants := []string{""}
+			for tenant, out := range outputs {
+				if out.f != nil {
+					out.f.Close()
+				}
+				if tenant != "" {
+					tenants = append(tenants, tenant)
+				}
+			}
+			for _, tenant := range tenants {
+				open(tenant)
+			}
+			flush()
+			close(done)
+		}
+	}
+}
+
+// Get the state of the aggregation writer
+func getAggWriterStats() AggWriterStats {
+	stats := AggWriterStats{
+		QueueDepth:  len(aggQueue),
+		QueueSize:   aggQueueSize,
+		Written:     atomic.LoadUint64(&aggWritten),
+		Dropped:     atomic.LoadUint64(&aggDropped),
+		WriteErrors: atomic.LoadUint64(&aggWriteErrors),
+	}
+	if d, ok := aggLastFlush.Load().(time.Duration); ok {
+		stats.LastFlushTime = d.String()
+	}
+	return stats
+}
+
+// Write out the aggregation writer metrics
+func writeAggWriterMetrics(mw *metricsWriter) {
+	stats := getAggWriterStats()
+	mw.header("console_node_agg_queue_depth", "gauge", "Lines waiting to be written to the aggregation log")
+	mw.value("console_node_agg_queue_depth", float64(stats.QueueDepth))
+	mw.header("console_node_agg_lines_written_total", "counter", "Lines written to the aggregation log")
+	mw.value("console_node_agg_lines_written_total", float64(stats.Written))
+	mw.header("console_node_agg_lines_dropped_total", "counter", "Lines dropped because the aggregation queue was full")
+	mw.value("console_node_agg_lines_dropped_total", float64(stats.Dropped))
+	mw.header("console_node_agg_write_errors_total", "counter", "Errors writing the aggregation log")
+	mw.value("console_node_agg_write_errors_total", float64(stats.WriteErrors))
+	if d, ok := aggLastFlush.Load().(time.Duration); ok {
+		mw.header("console_node_agg_last_flush_seconds", "gauge", "Time taken by the last flush of the aggregation log")
+		mw.value("console_node_agg_last_flush_seconds", d.Seconds())
+	}
+}
@@ -0,0 +1,548 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the audit log of the sessions and requests on the consoles
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources of audit records
+const (
+	auditSourceAPI    string = "api"    // requests made to this service
+	auditSourceConman string = "conman" // sessions reported by conman in the console log
+)
+
+// Access modes of a console session
+const (
+	auditReadOnly  string = "read-only"
+	auditReadWrite string = "read-write"
+	auditForce     string = "force"
+)
+
+// Configuration of the audit log
+var auditEnabled bool = true
+var auditLogDir string = "/var/log/conman.audit" // directory holding the audit log
+var auditInput bool = false                      // record the input sent to the consoles
+var auditExport bool = true                      // copy the records to the aggregation log
+var auditMaxSizeMB int = 100                     // size of the audit log before it is rolled
+var auditMaxFiles int = 0                        // rolled audit logs kept, 0 keeps all of them
+var auditQueryLimit int = 1000                   // most records returned by a query
+
+// AuditRecord - a single entry in the audit log
+type AuditRecord struct {
+	ID       int64             `json:"id"`
+	Time     time.Time         `json:"time"`
+	Pod      string            `json:"pod"`
+	Xname    string            `json:"xname"`
+	Source   string            `json:"source"`
+	Action   string            `json:"action"`
+	User     string            `json:"user"`
+	Remote   string            `json:"remote,omitempty"`
+	Mode     string            `json:"mode,omitempty"`
+	Session  int64             `json:"session,omitempty"`
+	Duration string            `json:"duration,omitempty"`
+	Status   int               `json:"status,omitempty"`
+	Input    string            `json:"input,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+// A conman session that has not ended yet
+type auditOpenSession struct {
+	id    int64
+	start time.Time
+	mode  string
+}
+
+// Globals holding the audit log
+var auditMutex = &sync.Mutex{}
+var auditFile *os.File = nil
+var lastAuditID int64 = 0
+var auditSessions map[string][]auditOpenSession = make(map[string][]auditOpenSession) // [xname/user,sessions]
+
+// Conman reports clients joining and leaving a console in the console log
+var conmanSessionRe = regexp.MustCompile(`^<ConMan> Console \[[^\]]*\] (joined|departed|stolen) by <([^>]*)>(?: on (\S+))?`)
+
+// Name of the audit log file of this pod
+func auditLogFile() string {
+	return fmt.Sprintf("%s/audit-%s.jsonl", auditLogDir, podName)
+}
+
+// Read the audit configuration from the environment and open the audit log
+func initAudit() {
+	if val := os.Getenv("AUDIT_ENABLE"); val != "" {
+		auditEnabled = isTrue(val)
+	}
+	if val := os.Getenv("AUDIT_LOG_DIR"); val != "" {
+		auditLogDir = val
+	}
+	if val := os.Getenv("AUDIT_INPUT"); val != "" {
+		auditInput = isTrue(val)
+	}
+	if val := os.Getenv("AUDIT_EXPORT"); val != "" {
+		auditExport = isTrue(val)
+	}
+	readSingleEnvVarInt("AUDIT_MAX_SIZE_MB", &auditMaxSizeMB, 1, 100000)
+	readSingleEnvVarInt("AUDIT_MAX_FILES", &auditMaxFiles, 0, 100000)
+	readSingleEnvVarInt("AUDIT_QUERY_LIMIT", &auditQueryLimit, 10, 1000000)
+	log.Printf("Audit log: enabled:%v, file:%s, input:%v, export:%v",
+		auditEnabled, auditLogFile(), auditInput, auditExport)
+	if !auditEnabled {
+		return
+	}
+
+	if _, err := ensureDirPresent(auditLogDir, 0700); err != nil {
+		log.Printf("Unable to create audit log directory: %s", err)
+	}
+
+	// carry on the record ids from the existing log
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	files := []string{auditLogFile()}
+	if rolled := auditRolledFiles(); len(rolled) > 0 {
+		files = append([]string{rolled[len(rolled)-1].name}, files...)
+	}
+	for _, fn := range files {
+		readAuditFile(fn, func(rec AuditRecord) {
+			if rec.ID > lastAuditID {
+				lastAuditID = rec.ID
+			}
+		})
+	}
+	openAuditFile()
+}
+
+// Open the audit log for appending
+func openAuditFile() {
+	// NOTE: auditMutex must be held by the caller
+	f, err := os.OpenFile(auditLogFile(), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		log.Printf("Unable to open audit log %s: %s", auditLogFile(), err)
+		auditFile = nil
+		return
+	}
+	auditFile = f
+}
+
+// A rolled audit log and its number
+type auditRolledFile struct {
+	name string
+	num  int
+}
+
+// Find the rolled audit logs of this pod, oldest first
+func auditRolledFiles() []auditRolledFile {
+	matches, _ := filepath.Glob(auditLogFile() + ".*")
+	var rolled []auditRolledFile
+	for _, fn := range matches {
+		num, err := strconv.Atoi(strings.TrimPrefix(fn, auditLogFile()+"."))
+		if err != nil || num < 1 {
+			continue
+		}
+		rolled = append(rolled, auditRolledFile{name: fn, num: num})
+	}
+	sort.Slice(rolled, func(i, j int) bool { return rolled[i].num < rolled[j].num })
+	return rolled
+}
+
+// Move the audit log aside once it gets too large
+func rollAuditFile() {
+	// NOTE: auditMutex must be held by the caller
+	fi, err := auditFile.Stat()
+	if err != nil || fi.Size() < int64(auditMaxSizeMB)*1024*1024 {
+		return
+	}
+
+	// each roll gets the next number so no earlier records are replaced
+	rolled := auditRolledFiles()
+	next := 1
+	if len(rolled) > 0 {
+		next = rolled[len(rolled)-1].num + 1
+	}
+	dest := fmt.Sprintf("%s.%d", auditLogFile(), next)
+	if _, err := os.Stat(dest); err == nil {
+		log.Printf("Unable to roll audit log, %s already exists", dest)
+		return
+	}
+	log.Printf("Rolling audit log %s to %s", auditLogFile(), dest)
+	auditFile.Close()
+	if err := os.Rename(auditLogFile(), dest); err != nil {
+		log.Printf("Unable to roll audit log: %s", err)
+	} else {
+		rolled = append(rolled, auditRolledFile{name: dest, num: next})
+	}
+	openAuditFile()
+
+	// only remove the oldest records when a retention limit is configured
+	if auditMaxFiles > 0 {
+		for len(rolled) > auditMaxFiles {
+			log.Printf("Removing audit log %s over the retention of %d files", rolled[0].name, auditMaxFiles)
+			if err := os.Remove(rolled[0].name); err != nil {
+				log.Printf("Unable to remove audit log %s: %s", rolled[0].name, err)
+				break
+			}
+			rolled = rolled[1:]
+		}
+	}
+}
+
+// Add a record to the audit log, returning its id
+func writeAudit(rec AuditRecord) int64 {
+	if !auditEnabled {
+		return 0
+	}
+
+	auditMutex.Lock()
+	lastAuditID++
+	rec.ID = lastAuditID
+	rec.Pod = podName
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		auditMutex.Unlock()
+		log.Printf("Unable to encode audit record: %s", err)
+		return 0
+	}
+	if auditFile == nil {
+		openAuditFile()
+	}
+	if auditFile != nil {
+		rollAuditFile()
+	}
+	if auditFile != nil {
+		// NOTE: the records are rare enough to push each one to disk
+		if _, err := auditFile.Write(append(data, '\n')); err != nil {
+			log.Printf("Error writing audit log: %s", err)
+		} else {
+			auditFile.Sync()
+		}
+	}
+	auditMutex.Unlock()
+
+	// pass the record on to the log shipping through the aggregation log
+	if auditExport {
+		writeToAggLog("console.audit: " + string(data))
+	}
+	return rec.ID
+}
+
+// Read the records from an audit log file
+func readAuditFile(fn string, f func(AuditRecord)) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		f(rec)
+	}
+}
+
+// Classify the access a request to the console api needs
+func auditRequestMode(op, method string) string {
+	switch op {
+	case "input", "automation":
+		return auditReadWrite
+	case "power", "reconnect":
+		if method != http.MethodGet {
+			return auditReadWrite
+		}
+	}
+	return auditReadOnly
+}
+
+// Keeps the status of the response for the audit record
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// Note the status as it is written
+func (aw *auditResponseWriter) WriteHeader(sc int) {
+	if aw.status == 0 {
+		aw.status = sc
+	}
+	aw.ResponseWriter.WriteHeader(sc)
+}
+
+// Note the implied status of a response written without a header
+func (aw *auditResponseWriter) Write(b []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	return aw.ResponseWriter.Write(b)
+}
+
+// Pass flushes on for the streaming responses
+func (aw *auditResponseWriter) Flush() {
+	if fl, ok := aw.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// Record a request against a console while it is handled
+func auditConsoleRequest(w http.ResponseWriter, r *http.Request, xname, op string, handler func(http.ResponseWriter)) {
+	if !auditEnabled {
+		handler(w)
+		return
+	}
+
+	rec := AuditRecord{
+		Xname:   xname,
+		Source:  auditSourceAPI,
+		User:    requestUser(r),
+		Remote:  r.RemoteAddr,
+		Mode:    auditRequestMode(op, r.Method),
+		Details: map[string]string{"operation": op, "method": r.Method},
+	}
+
+	// changes to a console are bracketed so an interrupted session still shows
+	start := time.Now()
+	if rec.Mode == auditReadWrite {
+		rec.Action = "session_start"
+		rec.Session = writeAudit(rec)
+	}
+	aw := &auditResponseWriter{ResponseWriter: w}
+	handler(aw)
+
+	rec.Time = time.Time{}
+	rec.Action = "request"
+	if rec.Mode == auditReadWrite {
+		rec.Action = "session_end"
+	}
+	rec.Duration = time.Since(start).String()
+	rec.Status = aw.status
+	writeAudit(rec)
+}
+
+// Record a request against a console that was refused
+func auditDenied(r *http.Request, xname, op string, reason error) {
+	writeAudit(AuditRecord{
+		Xname:   xname,
+		Source:  auditSourceAPI,
+		Action:  "denied",
+		User:    requestUser(r),
+		Remote:  r.RemoteAddr,
+		Mode:    auditRequestMode(op, r.Method),
+		Details: map[string]string{"operation": op, "method": r.Method, "reason": reason.Error()},
+	})
+}
+
+// Record input sent to a console, if configured to do so
+func auditConsoleInput(r *http.Request, xname, mode, data string) {
+	if !auditInput {
+		return
+	}
+	writeAudit(AuditRecord{
+		Xname:   xname,
+		Source:  auditSourceAPI,
+		Action:  "input",
+		User:    requestUser(r),
+		Remote:  r.RemoteAddr,
+		Mode:    auditReadWrite,
+		Input:   data,
+		Details: map[string]string{"input_mode": mode},
+	})
+}
+
+// Record a power action requested on the node of a console
+// NOTE: the record is the marker of the action, conmand owns the console log
+func auditPowerAction(r *http.Request, xname, action, service string, failure error) {
+	rec := AuditRecord{
+		Xname:   xname,
+		Source:  auditSourceAPI,
+		Action:  "power",
+		User:    requestUser(r),
+		Remote:  r.RemoteAddr,
+		Mode:    auditReadWrite,
+		Details: map[string]string{"power_action": action, "service": service},
+	}
+	if failure != nil {
+		rec.Action = "power_failed"
+		rec.Details["error"] = failure.Error()
+	}
+	writeAudit(rec)
+}
+
+// Look for conman reporting a client joining or leaving the console
+func checkAuditSession(xname, text string, when time.Time) {
+	if !auditEnabled {
+		return
+	}
+	if _, rest := splitConmanTimestamp(text); isConmanMessage(rest) {
+		text = rest
+	}
+	if !isConmanMessage(text) {
+		return
+	}
+	m := conmanSessionRe.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+
+	rec := AuditRecord{
+		Time:   when,
+		Xname:  xname,
+		Source: auditSourceConman,
+		User:   m[2],
+	}
+	if m[3] != "" {
+		rec.Details = map[string]string{"tty": m[3]}
+	}
+	key := xname + "/" + m[2]
+	switch m[1] {
+	case "joined", "stolen":
+		// NOTE: a stolen console was taken over by a client forcing its way in
+		rec.Action = "session_start"
+		rec.Mode = auditReadWrite
+		if m[1] == "stolen" {
+			rec.Mode = auditForce
+		}
+		id := writeAudit(rec)
+		auditMutex.Lock()
+		auditSessions[key] = append(auditSessions[key], auditOpenSession{id: id, start: when, mode: rec.Mode})
+		auditMutex.Unlock()
+	case "departed":
+		rec.Action = "session_end"
+		auditMutex.Lock()
+		if open := auditSessions[key]; len(open) > 0 {
+			rec.Session = open[0].id
+			rec.Mode = open[0].mode
+			rec.Duration = when.Sub(open[0].start).String()
+			if len(open) > 1 {
+				auditSessions[key] = open[1:]
+			} else {
+				delete(auditSessions, key)
+			}
+		}
+		auditMutex.Unlock()
+		writeAudit(rec)
+	}
+}
+
+// Gather the audit records that match the filters
+func queryAudit(xname, user, action, source string, since, until time.Time, limit int) []AuditRecord {
+	// NOTE: hold the lock so the log is not rolled while reading it
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	files := []string{auditLogFile()}
+	for _, rf := range auditRolledFiles() {
+		files = append(files, rf.name)
+	}
+	slices.Reverse(files[1:])
+
+	// work back from the newest file until there are enough records
+	recs := []AuditRecord{}
+	for _, fn := range files {
+		fileRecs := []AuditRecord{}
+		readAuditFile(fn, func(rec AuditRecord) {
+			if (xname != "" && rec.Xname != xname) || (user != "" && rec.User != user) ||
+				(action != "" && rec.Action != action) || (source != "" && rec.Source != source) {
+				return
+			}
+			if (!since.IsZero() && rec.Time.Before(since)) || (!until.IsZero() && rec.Time.After(until)) {
+				return
+			}
+			fileRecs = append(fileRecs, rec)
+		})
+		recs = append(fileRecs, recs...)
+		if len(recs) >= limit {
+			break
+		}
+	}
+	// keep the most recent records
+	if len(recs) > limit {
+		recs = recs[len(recs)-limit:]
+	}
+	return recs
+}
+
+// Handle a request for the audit records
+func doAudit(w http.ResponseWriter, r *http.Request) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+	if !auditEnabled {
+		sendJSONError(w, http.StatusServiceUnavailable, "The audit log is not enabled")
+		return
+	}
+
+	q := r.URL.Query()
+	since, err := parseTimeParam(q.Get("since"))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since: %s", q.Get("since")))
+		return
+	}
+	until, err := parseTimeParam(q.Get("until"))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid until: %s", q.Get("until")))
+		return
+	}
+	limit := auditQueryLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit: %s", v))
+			return
+		}
+		if n < limit {
+			limit = n
+		}
+	}
+	recs := queryAudit(q.Get("xname"), q.Get("user"), q.Get("action"), q.Get("source"), since, until, limit)
+	allowed := recs[:0]
+	for _, rec := range recs {
+		if authAllowsXname(r, rec.Xname) {
+			allowed = append(allowed, rec)
+		}
+	}
+	SendResponseJSON(w, http.StatusOK, allowed)
+}
@@ -0,0 +1,492 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the authentication and authorization of the http api
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// Classes of endpoints, each role also grants the ones before it
+const (
+	roleHealth      string = "health"      // health and metrics
+	roleLogs        string = "logs"        // reading console output and state
+	roleInteractive string = "interactive" // sending input and changing consoles
+	roleAdmin       string = "admin"       // the audit log
+)
+
+// Order of the roles from least to most access
+var roleOrder = []string{roleHealth, roleLogs, roleInteractive, roleAdmin}
+
+// Configuration of the api authentication
+var authEnabled bool = false
+var authAllowAnonymousWrite bool = false        // allow changing consoles without a token when auth is off
+var authTrustedProxies []*net.IPNet = nil       // proxies trusted to pass on the user in a header
+var authIssuer string = ""                      // required token issuer, empty for any
+var authAudience string = ""                    // required token audience, empty for any
+var authJwksURL string = ""                     // url to fetch the signing keys from
+var authJwksFile string = ""                    // local file of signing keys
+var authJwksRefreshSecs int = 300               // time between refreshes of the signing keys
+var authLeewaySecs int = 60                     // allowed clock skew checking token times
+var authUserClaim string = "preferred_username" // claim holding the user name
+var authRolesClaim string = "roles"             // claim holding the roles, dotted for nested claims
+var authRolePrefix string = ""                  // prefix on the role names in the token
+var authXnamesClaim string = "xnames"           // claim limiting the consoles that may be accessed
+var authTenantsClaim string = "tenants"         // claim limiting the tenants that may be accessed
+
+// Token that processes inside this pod use to call the api
+const authLocalTokenFile string = "/tmp/console-node.token"
+
+// Algorithms accepted on tokens
+var authAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+}
+
+// AuthClaims - who made a request and what they may access
+type AuthClaims struct {
+	User    string
+	Roles   []string
+	Xnames  []string // patterns of the consoles, empty for all
+	Tenants []string // tenants, empty for all
+}
+
+// Globals holding the signing keys
+var authMutex = &sync.RWMutex{}
+var authKeys *jose.JSONWebKeySet = nil
+var authKeysTried time.Time
+var authLocalToken string = ""
+
+// Key to find the claims in a request context
+type authContextKey struct{}
+
+// Read the authentication configuration from the environment
+func initAuth() {
+	if val := os.Getenv("AUTH_ENABLE"); val != "" {
+		authEnabled = isTrue(val)
+	}
+	if val := os.Getenv("AUTH_ALLOW_ANONYMOUS_WRITE"); val != "" {
+		authAllowAnonymousWrite = isTrue(val)
+	}
+	authTrustedProxies = parseTrustedProxies(os.Getenv("AUTH_TRUSTED_PROXIES"))
+	for env, v := range map[string]*string{
+		"AUTH_ISSUER":        &authIssuer,
+		"AUTH_AUDIENCE":      &authAudience,
+		"AUTH_JWKS_URL":      &authJwksURL,
+		"AUTH_JWKS_FILE":     &authJwksFile,
+		"AUTH_USER_CLAIM":    &authUserClaim,
+		"AUTH_ROLES_CLAIM":   &authRolesClaim,
+		"AUTH_ROLE_PREFIX":   &authRolePrefix,
+		"AUTH_XNAMES_CLAIM":  &authXnamesClaim,
+		"AUTH_TENANTS_CLAIM": &authTenantsClaim,
+	} {
+		if val := os.Getenv(env); val != "" {
+			*v = val
+		}
+	}
+	readSingleEnvVarInt("AUTH_JWKS_REFRESH_SEC", &authJwksRefreshSecs, 10, 86400)
+	readSingleEnvVarInt("AUTH_LEEWAY_SEC", &authLeewaySecs, 0, 3600)
+	log.Printf("API authentication: enabled:%v, issuer:%s, audience:%s, jwks url:%s, jwks file:%s, anonymous write:%v, trusted proxies:%d",
+		authEnabled, authIssuer, authAudience, authJwksURL, authJwksFile, authAllowAnonymousWrite, len(authTrustedProxies))
+
+	// processes in this pod, like the conman reset command, use a local token
+	// NOTE: needed even without authentication to change consoles
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err == nil {
+		authLocalToken = hex.EncodeToString(buf)
+		if err := os.WriteFile(authLocalTokenFile, []byte(authLocalToken), 0600); err != nil {
+			log.Printf("Unable to write the local api token: %s", err)
+		}
+	}
+
+	if !authEnabled {
+		if !authAllowAnonymousWrite {
+			log.Printf("API authentication is disabled, changing consoles is limited to this pod")
+		}
+		return
+	}
+	if authJwksURL == "" && authJwksFile == "" {
+		// NOTE: fail closed, no token can be verified without keys
+		log.Printf("Error: API authentication is enabled without AUTH_JWKS_URL or AUTH_JWKS_FILE")
+	}
+
+	loadAuthKeys()
+	go watchAuthKeys()
+}
+
+// Parse a comma separated list of proxy addresses or networks
+func parseTrustedProxies(val string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(val, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			log.Printf("Ignoring invalid trusted proxy %q: %s", s, err)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// Check if a request came through one of the trusted proxies
+func fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range authTrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load the signing keys from the configured source
+func loadAuthKeys() error {
+	authMutex.Lock()
+	authKeysTried = time.Now()
+	authMutex.Unlock()
+
+	var data []byte
+	var err error
+	if authJwksFile != "" {
+		data, err = os.ReadFile(authJwksFile)
+	} else if authJwksURL != "" {
+		var sc int
+		data, sc, err = getURL(authJwksURL, nil)
+		if err == nil && sc != http.StatusOK {
+			err = fmt.Errorf("fetching %s returned sc=%d", authJwksURL, sc)
+		}
+	} else {
+		return errors.New("no signing keys configured")
+	}
+	if err != nil {
+		log.Printf("Unable to load the api signing keys: %s", err)
+		return err
+	}
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		log.Printf("Unable to parse the api signing keys: %s", err)
+		return err
+	}
+
+	authMutex.Lock()
+	defer authMutex.Unlock()
+	authKeys = &keys
+	log.Printf("Loaded %d api signing keys", len(keys.Keys))
+	return nil
+}
+
+// Periodically refresh the signing keys
+func watchAuthKeys() {
+	for {
+		time.Sleep(time.Duration(authJwksRefreshSecs) * time.Second)
+		loadAuthKeys()
+	}
+}
+
+// Find the signing key for a token
+func findAuthKey(kid string) (interface{}, error) {
+	authMutex.RLock()
+	keys, tried := authKeys, authKeysTried
+	authMutex.RUnlock()
+
+	// the keys may have been rotated since they were loaded
+	if (keys == nil || len(keys.Key(kid)) == 0) && time.Since(tried) > 30*time.Second {
+		if err := loadAuthKeys(); err == nil {
+			authMutex.RLock()
+			keys = authKeys
+			authMutex.RUnlock()
+		}
+	}
+	if keys == nil {
+		return nil, errors.New("no signing keys available")
+	}
+	if kid == "" && len(keys.Keys) == 1 {
+		return keys.Keys[0].Key, nil
+	}
+	if found := keys.Key(kid); len(found) > 0 {
+		return found[0].Key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+// Pull a claim out of the token, following dots into nested claims
+func lookupClaim(claims map[string]interface{}, name string) interface{} {
+	var cur interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// Read a claim as a list of strings
+func claimStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return nil
+		}
+		return strings.Fields(strings.ReplaceAll(t, ",", " "))
+	case []interface{}:
+		var vals []string
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				vals = append(vals, s)
+			}
+		}
+		return vals
+	}
+	return nil
+}
+
+// Verify a bearer token and gather its claims
+func verifyToken(raw string) (*AuthClaims, error) {
+	tok, err := jwt.ParseSigned(raw, authAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	if len(tok.Headers) == 0 {
+		return nil, errors.New("token has no signature")
+	}
+	key, err := findAuthKey(tok.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+	var std jwt.Claims
+	var all map[string]interface{}
+	if err := tok.Claims(key, &std, &all); err != nil {
+		return nil, err
+	}
+	exp := jwt.Expected{Issuer: authIssuer, Time: time.Now()}
+	if authAudience != "" {
+		exp.AnyAudience = jwt.Audience{authAudience}
+	}
+	if err := std.ValidateWithLeeway(exp, time.Duration(authLeewaySecs)*time.Second); err != nil {
+		return nil, err
+	}
+	// NOTE: the expiry is only checked when present, tokens that never expire are refused
+	if std.Expiry == nil {
+		return nil, errors.New("token has no expiry")
+	}
+
+	claims := &AuthClaims{User: std.Subject}
+	if u, ok := lookupClaim(all, authUserClaim).(string); ok && u != "" {
+		claims.User = u
+	}
+	for _, role := range claimStrings(lookupClaim(all, authRolesClaim)) {
+		if strings.HasPrefix(role, authRolePrefix) {
+			claims.Roles = append(claims.Roles, strings.TrimPrefix(role, authRolePrefix))
+		}
+	}
+	claims.Xnames = claimStrings(lookupClaim(all, authXnamesClaim))
+	claims.Tenants = claimStrings(lookupClaim(all, authTenantsClaim))
+	return claims, nil
+}
+
+// Check the claims give at least the required role
+func (c *AuthClaims) hasRole(need string) bool {
+	needIdx := 0
+	for i, r := range roleOrder {
+		if r == need {
+			needIdx = i
+		}
+	}
+	for _, have := range c.Roles {
+		for i, r := range roleOrder {
+			if r == have && i >= needIdx {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check the claims allow access to a console
+func (c *AuthClaims) allowsXname(xname string) bool {
+	// NOTE: a token limited to tenants can not reach consoles without one
+	if len(c.Tenants) > 0 {
+		tenant := consoleTenant(xname)
+		allowed := false
+		for _, t := range c.Tenants {
+			if tenant != "" && t == tenant {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	if len(c.Xnames) == 0 {
+		return true
+	}
+	for _, pat := range c.Xnames {
+		if ok, _ := path.Match(pat, xname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the claims of an authenticated request
+func requestClaims(r *http.Request) *AuthClaims {
+	c, _ := r.Context().Value(authContextKey{}).(*AuthClaims)
+	return c
+}
+
+// Authenticate a request, returning the status to fail it with
+func authenticate(r *http.Request, need string) (*AuthClaims, int, error) {
+	hdr := r.Header.Get("Authorization")
+	if !strings.HasPrefix(hdr, "Bearer ") {
+		return nil, http.StatusUnauthorized, errors.New("missing bearer token")
+	}
+	if claims := localTokenClaims(r); claims != nil {
+		return claims, 0, nil
+	}
+	claims, err := verifyToken(strings.TrimSpace(strings.TrimPrefix(hdr, "Bearer ")))
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+	if !claims.hasRole(need) {
+		return nil, http.StatusForbidden, fmt.Errorf("the %s role is required", need)
+	}
+	return claims, 0, nil
+}
+
+// Get the claims of a request made with the local token of this pod
+func localTokenClaims(r *http.Request) *AuthClaims {
+	raw := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if authLocalToken != "" && subtle.ConstantTimeCompare([]byte(raw), []byte(authLocalToken)) == 1 {
+		return &AuthClaims{User: "console-node", Roles: []string{roleAdmin}}
+	}
+	return nil
+}
+
+// Check a request that changes a console while authentication is off, only
+// processes in this pod may do so unless anonymous writes are allowed
+func authorizeAnonymousWrite(r *http.Request) (*AuthClaims, int, error) {
+	if authAllowAnonymousWrite {
+		return nil, 0, nil
+	}
+	if claims := localTokenClaims(r); claims != nil {
+		return claims, 0, nil
+	}
+	return nil, http.StatusForbidden,
+		errors.New("changing a console requires API authentication, see AUTH_ENABLE and AUTH_ALLOW_ANONYMOUS_WRITE")
+}
+
+// Reject a request that failed authentication
+func sendAuthError(w http.ResponseWriter, sc int, err error) {
+	if sc == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="console-node"`)
+	}
+	sendJSONError(w, sc, err.Error())
+}
+
+// Wrap a handler so it requires a role
+func requireRole(need string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authEnabled {
+			handler(w, r)
+			return
+		}
+		claims, sc, err := authenticate(r, need)
+		if err != nil {
+			log.Printf("Denied %s %s from %s: %s", r.Method, r.URL.Path, r.RemoteAddr, err)
+			sendAuthError(w, sc, err)
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, claims)))
+	}
+}
+
+// Role needed for an operation on a single console
+func consoleOpRole(op, method string) string {
+	if auditRequestMode(op, method) == auditReadWrite {
+		return roleInteractive
+	}
+	return roleLogs
+}
+
+// Check the request may access a console
+func authAllowsXname(r *http.Request, xname string) bool {
+	if c := requestClaims(r); c != nil {
+		return c.allowsXname(xname)
+	}
+	return true
+}
+
+// Limit a list of consoles to the ones the request may access
+func authFilterXnames(r *http.Request, xnames []string) []string {
+	c := requestClaims(r)
+	if c == nil || (len(c.Xnames) == 0 && len(c.Tenants) == 0) {
+		return xnames
+	}
+	allowed := make([]string, 0, len(xnames))
+	for _, xn := range xnames {
+		if c.allowsXname(xn) {
+			allowed = append(allowed, xn)
+		}
+	}
+	return allowed
+}
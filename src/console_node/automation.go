@@ -0,0 +1,434 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the expect style automation of a console
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Actions that may be taken in an automation script
+const (
+	stepSend   string = "send"   // send text to the console
+	stepExpect string = "expect" // wait for the output to match a pattern
+	stepSleep  string = "sleep"  // wait a fixed time
+	stepBreak  string = "break"  // send a serial break
+	stepSysRq  string = "sysrq"  // send a serial break and a SysRq key
+)
+
+// Limits on automation scripts
+const maxAutomationSteps int = 100
+const maxTranscriptSize int = 1024 * 1024
+
+var automationTimeoutSecs int = 300     // default time a whole script may take
+var automationMaxTimeoutSecs int = 3600 // longest time a caller may ask for
+var automationExpectSecs int = 30       // default time to wait for an expect step
+
+// AutomationStep - a single step of an automation script
+type AutomationStep struct {
+	Action     string  `json:"action"`
+	Data       string  `json:"data,omitempty"`
+	Pattern    string  `json:"pattern,omitempty"`
+	TimeoutSec float64 `json:"timeout_sec,omitempty"`
+	Capture    string  `json:"capture,omitempty"`
+	Key        string  `json:"key,omitempty"`
+	Seconds    float64 `json:"seconds,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// AutomationScript - the script to run against a console
+type AutomationScript struct {
+	Steps      []AutomationStep `json:"steps"`
+	TimeoutSec int              `json:"timeout_sec,omitempty"`
+}
+
+// AutomationProgress - report on a single step of a running script
+type AutomationProgress struct {
+	Step    int    `json:"step"`
+	Action  string `json:"action"`
+	Status  string `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	Elapsed string `json:"elapsed"`
+}
+
+// AutomationResult - the outcome of a whole script
+type AutomationResult struct {
+	Status     string            `json:"status"`
+	FailedStep *int              `json:"failed_step,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Captures   map[string]string `json:"captures"`
+	Transcript string            `json:"transcript"`
+	Elapsed    string            `json:"elapsed"`
+}
+
+// Consoles with a script running against them
+var automationMutex = &sync.Mutex{}
+var automationRunning map[string]bool = make(map[string]bool) // [xname,running]
+
+// Escape sequences that do not belong in the output being matched
+var ansiEscapeRe = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07]*\x07|[()][0-9A-Za-z]|[=>78])`)
+
+// Start of an escape sequence cut off at the end of a read
+var ansiPartialRe = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*|\][^\x07]*|[()])?$`)
+
+// Longest escape sequence held back waiting for the rest of it
+const maxEscapeLen int = 256
+
+// Read the automation configuration from the environment
+func initAutomation() {
+	readSingleEnvVarInt("AUTOMATION_TIMEOUT_SEC", &automationTimeoutSecs, 10, automationMaxTimeoutSecs)
+	readSingleEnvVarInt("AUTOMATION_EXPECT_SEC", &automationExpectSecs, 1, automationMaxTimeoutSecs)
+}
+
+// A conman client session used to run a script
+type automationSession struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	mu     sync.Mutex
+	output []byte        // latest output with escape sequences removed
+	cursor int           // output before this has already been matched
+	notify chan struct{} // signaled when more output arrives
+	done   chan struct{} // closed when the session ends
+}
+
+// Start a conman client session with the console
+func startAutomationSession(ctx context.Context, xname string) (*automationSession, error) {
+	// NOTE: joining the console keeps any interactive users connected
+	cmd := exec.CommandContext(ctx, "conman", "-j", "-Q", xname)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start conman client: %s", err)
+	}
+
+	as := &automationSession{
+		cmd:    cmd,
+		stdin:  stdin,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go as.readOutput(stdout)
+	return as, nil
+}
+
+// Collect the output of the console as it arrives
+func (as *automationSession) readOutput(stdout io.Reader) {
+	defer close(as.done)
+	buf := make([]byte, 4096)
+	carry := ""
+	for {
+		n, err := stdout.Read(buf)
+		if n > 0 {
+			// an escape sequence may be split across reads, hold back the start of
+			// one until the rest arrives
+			text := carry + string(buf[:n])
+			carry = ""
+			if loc := ansiPartialRe.FindStringIndex(text); loc != nil && len(text)-loc[0] <= maxEscapeLen {
+				text, carry = text[:loc[0]], text[loc[0]:]
+			}
+			as.appendOutput(ansiEscapeRe.ReplaceAllString(text, ""))
+		}
+		if err != nil {
+			as.appendOutput(carry)
+			return
+		}
+	}
+}
+
+// Add to the output, dropping the oldest once it is over the size limit
+func (as *automationSession) appendOutput(text string) {
+	if text == "" {
+		return
+	}
+	as.mu.Lock()
+	as.output = append(as.output, text...)
+	if len(as.output) > maxTranscriptSize {
+		// drop a quarter at a time rather than on every read
+		drop := len(as.output) - maxTranscriptSize*3/4
+		as.output = append(as.output[:0], as.output[drop:]...)
+		as.cursor = max(as.cursor-drop, 0)
+	}
+	as.mu.Unlock()
+	select {
+	case as.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Send input to the console, it must already be escaped for the conman client
+func (as *automationSession) send(input string) error {
+	_, err := io.WriteString(as.stdin, input)
+	return err
+}
+
+// Wait for the output after the last match to match the pattern
+func (as *automationSession) expect(ctx context.Context, re *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ended := false
+	for {
+		as.mu.Lock()
+		out := as.output
+		if loc := re.FindSubmatchIndex(out[as.cursor:]); loc != nil {
+			var groups []string
+			for i := 0; i+1 < len(loc); i += 2 {
+				if loc[i] >= 0 {
+					groups = append(groups, string(out[as.cursor+loc[i]:as.cursor+loc[i+1]]))
+				} else {
+					groups = append(groups, "")
+				}
+			}
+			as.cursor += loc[1]
+			as.mu.Unlock()
+			return groups, nil
+		}
+		as.mu.Unlock()
+		if ended {
+			return nil, fmt.Errorf("console session ended waiting for %q", re.String())
+		}
+
+		select {
+		case <-as.notify:
+		case <-timer.C:
+			return nil, fmt.Errorf("timed out after %s waiting for %q", timeout, re.String())
+		case <-as.done:
+			// check the last of the output once more before giving up
+			ended = true
+		case <-ctx.Done():
+			return nil, fmt.Errorf("script timed out waiting for %q", re.String())
+		}
+	}
+}
+
+// Close the session and return the transcript
+func (as *automationSession) close() string {
+	// ask the client to disconnect, the context kills it if that does not work
+	as.send(conmanEscapeClose)
+	as.stdin.Close()
+	select {
+	case <-as.done:
+	case <-time.After(5 * time.Second):
+		as.cmd.Process.Kill()
+		<-as.done
+	}
+	as.cmd.Wait()
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return string(as.output)
+}
+
+// Check a script before running any of it
+func validateScript(script *AutomationScript) error {
+	if len(script.Steps) == 0 {
+		return fmt.Errorf("script has no steps")
+	}
+	if len(script.Steps) > maxAutomationSteps {
+		return fmt.Errorf("script has %d steps, the limit is %d", len(script.Steps), maxAutomationSteps)
+	}
+	for i := range script.Steps {
+		st := &script.Steps[i]
+		switch st.Action {
+		case stepSend:
+			if st.Data == "" {
+				return fmt.Errorf("step %d: send needs data", i)
+			}
+		case stepExpect:
+			re, err := regexp.Compile(st.Pattern)
+			if err != nil || st.Pattern == "" {
+				return fmt.Errorf("step %d: invalid pattern %q", i, st.Pattern)
+			}
+			st.re = re
+		case stepSleep:
+			if st.Seconds <= 0 || st.Seconds > float64(automationMaxTimeoutSecs) {
+				return fmt.Errorf("step %d: invalid sleep of %v seconds", i, st.Seconds)
+			}
+		case stepBreak:
+		case stepSysRq:
+			if _, _, err := buildConsoleInput(ConsoleInputRequest{Mode: inputSysRq, Key: st.Key}); err != nil {
+				return fmt.Errorf("step %d: %s", i, err)
+			}
+		default:
+			return fmt.Errorf("step %d: unknown action %q", i, st.Action)
+		}
+	}
+	return nil
+}
+
+// Run a single step of a script
+func runAutomationStep(ctx context.Context, as *automationSession, st *AutomationStep, captures map[string]string) (string, error) {
+	switch st.Action {
+	case stepSend:
+		return fmt.Sprintf("sent %d bytes", len(st.Data)), as.send(escapeConmanInput(st.Data))
+	case stepBreak:
+		return "sent serial break", as.send(conmanEscapeBreak)
+	case stepSysRq:
+		input, _, _ := buildConsoleInput(ConsoleInputRequest{Mode: inputSysRq, Key: st.Key})
+		return "sent SysRq " + st.Key, as.send(input)
+	case stepSleep:
+		select {
+		case <-time.After(time.Duration(st.Seconds * float64(time.Second))):
+		case <-ctx.Done():
+			return "", fmt.Errorf("script timed out while sleeping")
+		}
+		return "", nil
+	case stepExpect:
+		timeout := time.Duration(automationExpectSecs) * time.Second
+		if st.TimeoutSec > 0 {
+			timeout = time.Duration(st.TimeoutSec * float64(time.Second))
+		}
+		groups, err := as.expect(ctx, st.re, timeout)
+		if err != nil {
+			return "", err
+		}
+		if st.Capture != "" {
+			// capture the first group if there is one, otherwise the whole match
+			if len(groups) > 1 {
+				captures[st.Capture] = groups[1]
+			} else {
+				captures[st.Capture] = groups[0]
+			}
+		}
+		return fmt.Sprintf("matched %q", truncateForAudit(groups[0])), nil
+	}
+	return "", fmt.Errorf("unknown action %q", st.Action)
+}
+
+// Handle a request to run an automation script against a console
+func doConsoleAutomation(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'POST' calls
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	var script AutomationScript
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024*1024)).Decode(&script); err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid script: %s", err))
+		return
+	}
+	if err := validateScript(&script); err != nil {
+		sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	timeout := automationTimeoutSecs
+	if script.TimeoutSec > 0 {
+		timeout = script.TimeoutSec
+		if timeout > automationMaxTimeoutSecs {
+			timeout = automationMaxTimeoutSecs
+		}
+	}
+
+	// only one script at a time may drive a console
+	automationMutex.Lock()
+	if automationRunning[xname] {
+		automationMutex.Unlock()
+		sendJSONError(w, http.StatusConflict,
+			fmt.Sprintf("An automation script is already running on %s", xname))
+		return
+	}
+	automationRunning[xname] = true
+	automationMutex.Unlock()
+	defer func() {
+		automationMutex.Lock()
+		delete(automationRunning, xname)
+		automationMutex.Unlock()
+	}()
+
+	user := requestUser(r)
+	log.Printf("Automation script of %d steps on %s from %s (%s)", len(script.Steps), xname, user, r.RemoteAddr)
+	raiseEvent(xname, "console_automation", eventInfo,
+		fmt.Sprintf("%s started a script of %d steps on console %s", user, len(script.Steps), xname),
+		map[string]string{"user": user, "remote": r.RemoteAddr})
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	as, err := startAutomationSession(ctx, xname)
+	if err != nil {
+		sendJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// stream the progress of each step as it completes
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	startTime := time.Now()
+	result := AutomationResult{Status: "ok", Captures: make(map[string]string)}
+	for i := range script.Steps {
+		st := &script.Steps[i]
+		switch st.Action {
+		case stepSend:
+			auditConsoleInput(r, xname, inputText, st.Data)
+		case stepBreak, stepSysRq:
+			auditConsoleInput(r, xname, st.Action, st.Key)
+		}
+		detail, err := runAutomationStep(ctx, as, st, result.Captures)
+		prog := AutomationProgress{Step: i, Action: st.Action, Status: "ok", Detail: detail,
+			Elapsed: time.Since(startTime).String()}
+		if err != nil {
+			prog.Status = "failed"
+			prog.Detail = err.Error()
+			result.Status = "failed"
+			failed := i
+			result.FailedStep = &failed
+			result.Error = err.Error()
+		}
+		enc.Encode(prog)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// finish with the captured output
+	result.Transcript = as.close()
+	result.Elapsed = time.Since(startTime).String()
+	enc.Encode(struct {
+		Result AutomationResult `json:"result"`
+	}{result})
+}
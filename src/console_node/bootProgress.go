@@ -0,0 +1,457 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the tracking of the boot progress of the nodes
+// from the console output
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration of a single boot phase
+type bootPhase struct {
+	Name     string   `json:"name"`
+	Markers  []string `json:"markers"`
+	StallSec int      `json:"stall_sec"`
+	Restart  bool     `json:"restart"` // seeing this phase again means the node rebooted
+
+	res []*regexp.Regexp
+}
+
+// Default boot phases in the order they happen
+// NOTE: the last phase marks the boot as complete
+// NOTE: only the restart phases start a boot, so their markers are anchored
+// to the start of the line
+var bootPhases = []*bootPhase{
+	{Name: "post", Restart: true, Markers: []string{
+		`^\s*POST\b`,
+		`^\s*BIOS (Version|Date)\b`,
+		`^\s*Press \S+ to (enter|run) [Ss]etup`,
+	}},
+	{Name: "bootloader", Restart: true, Markers: []string{
+		`^\s*GNU GRUB `,
+		`^\s*iPXE \d`,
+		`^\s*>>Start PXE`,
+		`^\s*Booting from `,
+		`^\s*Loading Linux `,
+	}},
+	{Name: "kernel", Restart: true, Markers: []string{
+		`^(\[ *\d+\.\d+\] )?Linux version \d`,
+	}},
+	{Name: "initrd", Markers: []string{
+		`Trying to unpack rootfs image`,
+		`Unpacking initramfs`,
+		`Run /init as init process`,
+	}},
+	{Name: "systemd", Markers: []string{
+		`systemd\[1\]: Switching root`,
+		`systemd\[1\]: Reached target (Basic System|Multi-User System)`,
+	}},
+	{Name: "login", Markers: []string{
+		`\blogin: *$`,
+	}},
+}
+
+// Configuration of the boot tracking
+var bootMarkersFile string = ""                        // file with the phase configuration
+var bootStallSecs int = 1200                           // default time in a phase before the boot is stalled
+var bootHistory int = 10                               // number of completed boots to keep
+var bootCheckInterval time.Duration = 30 * time.Second // time between stall checks
+
+// BootRecord - the progress through a single boot of a node
+type BootRecord struct {
+	Start     time.Time            `json:"start"`
+	Phase     string               `json:"phase"`
+	Phases    map[string]time.Time `json:"phases"`
+	Completed bool                 `json:"completed"`
+	Stalled   bool                 `json:"stalled"`
+	Duration  string               `json:"duration,omitempty"`
+	Seconds   float64              `json:"seconds,omitempty"`
+
+	phaseIdx int
+}
+
+// BootStatus - current and recent boots of a node
+type BootStatus struct {
+	Xname   string       `json:"xname"`
+	Current *BootRecord  `json:"current,omitempty"`
+	History []BootRecord `json:"history"`
+}
+
+// The boot tracking of a single console
+type bootTracker struct {
+	current *BootRecord
+	history []BootRecord // most recent last
+}
+
+// Globals for the boot tracking
+var bootMutex = &sync.Mutex{}
+var bootTrackers map[string]*bootTracker = make(map[string]*bootTracker) // [xname,*bootTracker]
+
+// Read the boot tracking configuration
+func initBootProgress() {
+	readSingleEnvVarInt("BOOT_STALL_SEC", &bootStallSecs, 60, 86400)
+	readSingleEnvVarInt("BOOT_HISTORY", &bootHistory, 1, 1000)
+	if val := os.Getenv("BOOT_MARKERS_FILE"); val != "" {
+		bootMarkersFile = val
+	}
+
+	// the phases may be replaced from a file
+	if bootMarkersFile != "" {
+		if phases, err := readBootPhases(bootMarkersFile); err != nil {
+			log.Printf("Error reading boot markers from %s, using defaults: %s", bootMarkersFile, err)
+		} else {
+			log.Printf("Read %d boot phases from %s", len(phases), bootMarkersFile)
+			bootPhases = phases
+		}
+	}
+	if err := compileBootPhases(bootPhases); err != nil {
+		log.Printf("Error in boot markers: %s", err)
+	}
+
+	go watchBootStalls()
+}
+
+// Read the boot phase configuration from a file
+func readBootPhases(fn string) ([]*bootPhase, error) {
+	// NOTE: expecting a file of the form:
+	//  {"phases":[{"name":"post","markers":["BIOS Version"],"stall_sec":600,"restart":true},...]}
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Phases []*bootPhase `json:"phases"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := validateBootPhases(cfg.Phases); err != nil {
+		return nil, err
+	}
+	if err := compileBootPhases(cfg.Phases); err != nil {
+		return nil, err
+	}
+	return cfg.Phases, nil
+}
+
+// Check a boot phase configuration can track boots
+func validateBootPhases(phases []*bootPhase) error {
+	if len(phases) < 2 {
+		return fmt.Errorf("at least two boot phases are required")
+	}
+	names := make(map[string]bool)
+	restart := false
+	for i, p := range phases {
+		if p == nil || p.Name == "" {
+			return fmt.Errorf("boot phase %d has no name", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("boot phase %s is listed more than once", p.Name)
+		}
+		names[p.Name] = true
+		if len(p.Markers) == 0 {
+			return fmt.Errorf("boot phase %s has no markers", p.Name)
+		}
+		for _, m := range p.Markers {
+			// NOTE: an empty marker would match every line
+			if strings.TrimSpace(m) == "" {
+				return fmt.Errorf("boot phase %s has an empty marker", p.Name)
+			}
+		}
+		restart = restart || p.Restart
+	}
+	// boots only start on a restart phase
+	if !restart {
+		return fmt.Errorf("at least one boot phase must have restart set")
+	}
+	return nil
+}
+
+// Compile the markers of all phases
+func compileBootPhases(phases []*bootPhase) error {
+	for _, p := range phases {
+		p.res = nil
+		for _, m := range p.Markers {
+			re, err := regexp.Compile(m)
+			if err != nil {
+				return fmt.Errorf("phase %s marker %q: %s", p.Name, m, err)
+			}
+			p.res = append(p.res, re)
+		}
+	}
+	return nil
+}
+
+// Find the phase a line of console output marks, -1 if none
+func matchBootPhase(text string) int {
+	for i, p := range bootPhases {
+		for _, re := range p.res {
+			if re.MatchString(text) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Time allowed in a phase before the boot is considered stalled
+func (p *bootPhase) stallTime() time.Duration {
+	if p.StallSec > 0 {
+		return time.Duration(p.StallSec) * time.Second
+	}
+	return time.Duration(bootStallSecs) * time.Second
+}
+
+// Start tracking the boots of a console
+func startBootTracking(xname string) {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+	if _, ok := bootTrackers[xname]; !ok {
+		bootTrackers[xname] = &bootTracker{}
+	}
+}
+
+// Stop tracking the boots of a console that is no longer monitored
+func removeBootTracking(xname string) {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+	delete(bootTrackers, xname)
+}
+
+// Move the boot state of a console forward from a line of output
+func trackBootProgress(xname, text string, when time.Time) {
+	// lines added by conman itself are not part of the console output
+	if isConmanMessage(text) {
+		return
+	}
+	if ts, rest := splitConmanTimestamp(text); !ts.IsZero() {
+		text = rest
+	}
+
+	idx := matchBootPhase(text)
+	if idx < 0 {
+		return
+	}
+
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+	bt, ok := bootTrackers[xname]
+	if !ok {
+		return
+	}
+
+	cur := bt.current
+	switch {
+	case cur == nil && !bootPhases[idx].Restart:
+		// the start of this boot was not seen, so its length is unknown
+		return
+	case cur == nil || (bootPhases[idx].Restart && idx < cur.phaseIdx):
+		// going back to an early phase means the node has started over
+		bt.finishBoot()
+		bt.current = &BootRecord{
+			Start:    when,
+			Phase:    bootPhases[idx].Name,
+			Phases:   map[string]time.Time{bootPhases[idx].Name: when},
+			phaseIdx: idx,
+		}
+		log.Printf("Boot of %s started in phase %s", xname, bootPhases[idx].Name)
+	case idx > cur.phaseIdx:
+		cur.Phase = bootPhases[idx].Name
+		cur.Phases[cur.Phase] = when
+		cur.phaseIdx = idx
+		cur.Stalled = false
+	default:
+		// repeat of the current phase or late output from an earlier one
+		return
+	}
+
+	cur = bt.current
+	if cur.phaseIdx == len(bootPhases)-1 && !cur.Completed {
+		cur.Completed = true
+		d := when.Sub(cur.Start)
+		cur.Duration = d.String()
+		cur.Seconds = d.Seconds()
+		log.Printf("Boot of %s completed in %s", xname, cur.Duration)
+	}
+}
+
+// Move the current boot into the history
+func (bt *bootTracker) finishBoot() {
+	if bt.current == nil {
+		return
+	}
+	bt.history = append(bt.history, *bt.current)
+	if len(bt.history) > bootHistory {
+		bt.history = bt.history[len(bt.history)-bootHistory:]
+	}
+	bt.current = nil
+}
+
+// Periodically look for boots that have stopped making progress
+func watchBootStalls() {
+	for {
+		time.Sleep(bootCheckInterval)
+		checkBootStalls(time.Now())
+	}
+}
+
+// Flag the boots that have been in a phase too long
+func checkBootStalls(now time.Time) {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+	for xname, bt := range bootTrackers {
+		cur := bt.current
+		if cur == nil || cur.Completed || cur.Stalled {
+			continue
+		}
+		p := bootPhases[cur.phaseIdx]
+		if now.Sub(cur.Phases[p.Name]) > p.stallTime() {
+			cur.Stalled = true
+			log.Printf("Boot of %s stalled in phase %s since %s", xname, p.Name,
+				cur.Phases[p.Name].Format(time.RFC3339))
+		}
+	}
+}
+
+// Get the boot status of a console
+func getBootStatus(xname string) (BootStatus, bool) {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+	bt, ok := bootTrackers[xname]
+	if !ok {
+		return BootStatus{}, false
+	}
+	bs := BootStatus{Xname: xname, History: make([]BootRecord, 0, len(bt.history)+1)}
+	if bt.current != nil {
+		cur := *bt.current
+		bs.Current = &cur
+	}
+	// most recent boots first
+	for i := len(bt.history) - 1; i >= 0; i-- {
+		bs.History = append(bs.History, bt.history[i])
+	}
+	return bs, true
+}
+
+// Handle a request for the boot status of a single console
+func doConsoleBoot(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	bs, ok := getBootStatus(xname)
+	if !ok {
+		sendJSONError(w, http.StatusNotFound,
+			fmt.Sprintf("No boot tracking for console %s", xname))
+		return
+	}
+	SendResponseJSON(w, http.StatusOK, bs)
+}
+
+// Handle a request for the boot status of all monitored consoles
+func doBoots(w http.ResponseWriter, r *http.Request) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	// optionally only report the boots that are stalled
+	stalledOnly := isTrue(r.URL.Query().Get("stalled"))
+	xnames := authFilterXnames(r, getCurrNodeXnames())
+	sort.Strings(xnames)
+	resp := make([]BootStatus, 0, len(xnames))
+	for _, xname := range xnames {
+		bs, ok := getBootStatus(xname)
+		if !ok {
+			continue
+		}
+		if stalledOnly && (bs.Current == nil || !bs.Current.Stalled) {
+			continue
+		}
+		resp = append(resp, bs)
+	}
+	SendResponseJSON(w, http.StatusOK, resp)
+}
+
+// Write out the boot metrics
+func writeBootMetrics(mw *metricsWriter) {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+
+	xnames := make([]string, 0, len(bootTrackers))
+	for xname := range bootTrackers {
+		xnames = append(xnames, xname)
+	}
+	sort.Strings(xnames)
+
+	mw.header("console_node_boot_phase", "gauge", "Current boot phase of the node, 1 for the active phase")
+	for _, xname := range xnames {
+		if cur := bootTrackers[xname].current; cur != nil {
+			mw.value("console_node_boot_phase", 1, "xname", xname, "phase", cur.Phase)
+		}
+	}
+	mw.header("console_node_boot_stalled", "gauge", "Whether the current boot of the node is stalled")
+	for _, xname := range xnames {
+		if cur := bootTrackers[xname].current; cur != nil {
+			stalled := 0.0
+			if cur.Stalled {
+				stalled = 1
+			}
+			mw.value("console_node_boot_stalled", stalled, "xname", xname)
+		}
+	}
+	mw.header("console_node_boot_duration_seconds", "gauge", "Duration of the last completed boot of the node")
+	for _, xname := range xnames {
+		bt := bootTrackers[xname]
+		if bt.current != nil && bt.current.Completed {
+			mw.value("console_node_boot_duration_seconds", bt.current.Seconds, "xname", xname)
+			continue
+		}
+		for i := len(bt.history) - 1; i >= 0; i-- {
+			if bt.history[i].Completed {
+				mw.value("console_node_boot_duration_seconds", bt.history[i].Seconds, "xname", xname)
+				break
+			}
+		}
+	}
+}
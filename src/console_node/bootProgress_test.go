@@ -0,0 +1,109 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the tests of the boot phase configuration
+
+package main
+
+import "testing"
+
+func TestValidateBootPhases(t *testing.T) {
+	tests := []struct {
+		name    string
+		phases  []*bootPhase
+		wantErr bool
+	}{
+		{name: "defaults", phases: bootPhases},
+		{
+			name: "restart and login",
+			phases: []*bootPhase{
+				{Name: "kernel", Restart: true, Markers: []string{`Linux version \d`}},
+				{Name: "login", Markers: []string{`login: *$`}},
+			},
+		},
+		{
+			name:    "one phase",
+			phases:  []*bootPhase{{Name: "kernel", Restart: true, Markers: []string{`Linux version`}}},
+			wantErr: true,
+		},
+		{
+			name: "no restart phase",
+			phases: []*bootPhase{
+				{Name: "kernel", Markers: []string{`Linux version`}},
+				{Name: "login", Markers: []string{`login:`}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "repeated name",
+			phases: []*bootPhase{
+				{Name: "kernel", Restart: true, Markers: []string{`Linux version`}},
+				{Name: "kernel", Markers: []string{`login:`}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing name",
+			phases: []*bootPhase{
+				{Name: "kernel", Restart: true, Markers: []string{`Linux version`}},
+				{Markers: []string{`login:`}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no markers",
+			phases: []*bootPhase{
+				{Name: "kernel", Restart: true, Markers: []string{`Linux version`}},
+				{Name: "login"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty marker",
+			phases: []*bootPhase{
+				{Name: "kernel", Restart: true, Markers: []string{`Linux version`}},
+				{Name: "login", Markers: []string{`login:`, ""}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "null phase",
+			phases: []*bootPhase{
+				{Name: "kernel", Restart: true, Markers: []string{`Linux version`}},
+				nil,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBootPhases(tt.phases)
+			if tt.wantErr && err == nil {
+				t.Errorf("expected an error")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2019-2025 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2019-2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -58,20 +58,22 @@ func configConman(forceConfigUpdate bool) bool {
 	// set up a thread to add log output to the aggregation file
 	allNodes := [3](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes, &currentMtnNodes}
 	for _, ar := range allNodes {
-		for nn := range *ar {
+		for nn, ni := range *ar {
+			// consoles held back until their tenant is known are not followed yet
+			if tenantPending(nn) {
+				continue
+			}
+
 			// make sure the node is being aggregated - no-op if already being done
 			aggregateFile(nn)
+
+			// keep the identity the console should report up to date
+			setExpectedIdentity(*ni)
 		}
 	}
 
-	// Make sure that we have a proper ssh console keypair deployed
-	// here and on the Mountain BMCs before starting conman.
-	// NOTE: this function will wait to return until keys are
-	//  present if there are Mountain consoles to configure
-	ensureMountainConsoleKeysPresent()
-
-	// return if there are any nodes
-	return (len(currentMtnNodes) + len(currentRvrNodes) + len(currentPdsNodes)) > 0
+	// return if there are any nodes that could be configured
+	return (len(currentMtnNodes) + len(currentRvrNodes) + len(currentPdsNodes) - numPendingCredentials() - numPendingTenants()) > 0
 }
 
 // Loop that starts / restarts conmand process
@@ -82,12 +84,24 @@ func runConman() {
 	//  the loop even if the user requests no updates
 	forceConfigUpdate := true
 	for {
+		// look up the creds of new consoles before taking the node lock
+		prefetchCredentials()
+
+		// Make sure that we have a proper ssh console keypair deployed
+		// here and on the Mountain BMCs before starting conman.
+		// NOTE: this function will wait to return until keys are
+		//  present if there are Mountain consoles to configure
+		waitForMountainConsoleKeys()
+
 		// do the configuration steps - force update on first pass
 		hasNodes := configConman(forceConfigUpdate)
 		forceConfigUpdate = false
 
 		// start the conmand process
-		if debugOnly {
+		if simulationMode() {
+			// the simulated consoles take the place of conmand until it would restart
+			runSimConsoles()
+		} else if debugOnly {
 			// not really running, just give a longer pause before re-running config
 			time.Sleep(25 * time.Second)
 			log.Printf("Sleeping the executeConman process")
@@ -117,6 +131,10 @@ func signalConmanHUP() {
 	if command != nil {
 		log.Print("Signaling conman with SIGHUP")
 		command.Process.Signal(syscall.SIGHUP)
+	} else if simulationMode() {
+		// the simulated consoles open their log for each write so they
+		// follow the rotation without being told
+		return
 	} else {
 		log.Print("Warning: Attempting to signal conman process when nil.")
 
@@ -142,6 +160,8 @@ func signalConmanTERM() {
 	if command != nil {
 		log.Print("Signaling conman with SIGTERM")
 		command.Process.Signal(syscall.SIGTERM)
+	} else if simulationMode() {
+		restartSimConsoles()
 	} else {
 		log.Print("Warning: Attempting to signal conman process when nil.")
 	}
@@ -237,6 +257,15 @@ func willUpdateConfig(fp *os.File) bool {
 	return retVal
 }
 
+// Log file option for a console that does not use the default log location
+func consoleLogOption(node *nodeConsoleInfo) string {
+	// NOTE: the consoles of tenants are logged in separate directories
+	if node.Tenant == "" {
+		return ""
+	}
+	return fmt.Sprintf(" log=\"%s\"", consoleLogFile(node.NodeName))
+}
+
 // Update the configuration file with the current endpoints
 func updateConfigFile(forceUpdate bool) {
 	// NOTE: in update config thread
@@ -281,30 +310,41 @@ func updateConfigFile(forceUpdate bool) {
 	for _, v := range currentPdsNodes {
 		rvrXNames = append(rvrXNames, v.BmcName)
 	}
+	pruneCredentials(rvrXNames)
 
-	// gather the river passwords
-	// NOTE: sometimes if vault hasn't been populated yet there may be no
-	// return values - try again for a while in that case.
-	passwords := getPasswordsWithRetries(rvrXNames, 15, 10)
+	// use the creds that have already been found
+	// NOTE: consoles still missing creds are left out of the configuration and
+	//  added once the creds are found in the background, so a slow or
+	//  incomplete vault does not hold up the node lock here.
+	passwords := cachedPasswords(rvrXNames)
 	previousPasswords = passwords
+	pending := make(map[string]string)
 
 	// Add River endpoints to the config file to be accessed by ipmi
 	for _, nodeCi := range currentRvrNodes {
 		// connect using ipmi
+		if tenantPending(nodeCi.NodeName) {
+			log.Printf("No tenant yet for %s, leaving it out of the configuration", nodeCi.NodeName)
+			continue
+		}
 		creds, ok := passwords[nodeCi.BmcName]
 		if !ok {
-			log.Printf("No creds record returned for %s", nodeCi.BmcName)
+			log.Printf("No creds yet for %s, leaving %s out of the configuration", nodeCi.BmcName, nodeCi.NodeName)
+			pending[nodeCi.NodeName] = nodeCi.BmcName
+			continue
 		}
-		log.Printf("console name=\"%s\" dev=\"ipmi:%s\" ipmiopts=\"U:%s,P:REDACTED,W:solpayloadsize\"\n",
+		log.Printf("console name=\"%s\" dev=\"ipmi:%s\" ipmiopts=\"U:%s,P:REDACTED,W:solpayloadsize\"%s\n",
 			nodeCi.NodeName,
 			nodeCi.BmcFqdn,
-			creds.Username)
+			creds.Username,
+			consoleLogOption(nodeCi))
 		// write the line to the config file
-		output := fmt.Sprintf("console name=\"%s\" dev=\"ipmi:%s\" ipmiopts=\"U:%s,P:%s,W:solpayloadsize\"\n",
+		output := fmt.Sprintf("console name=\"%s\" dev=\"ipmi:%s\" ipmiopts=\"U:%s,P:%s,W:solpayloadsize\"%s\n",
 			nodeCi.NodeName,
 			nodeCi.BmcFqdn,
 			creds.Username,
-			creds.Password)
+			creds.Password,
+			consoleLogOption(nodeCi))
 
 		// write the output line if there is anything present
 		if _, err = cf.WriteString(output); err != nil {
@@ -318,20 +358,28 @@ func updateConfigFile(forceUpdate bool) {
 	// Add Paradise endpoints to the config file to be accessed by ssh, but with passwords
 	for _, nodeCi := range currentPdsNodes {
 		// connect using ipmi
+		if tenantPending(nodeCi.NodeName) {
+			log.Printf("No tenant yet for %s, leaving it out of the configuration", nodeCi.NodeName)
+			continue
+		}
 		creds, ok := passwords[nodeCi.BmcName]
 		if !ok {
-			log.Printf("No creds record returned for %s", nodeCi.BmcName)
+			log.Printf("No creds yet for %s, leaving %s out of the configuration", nodeCi.BmcName, nodeCi.NodeName)
+			pending[nodeCi.NodeName] = nodeCi.BmcName
+			continue
 		}
-		log.Printf("console name=\"%s\" dev=\"/usr/bin/ssh-pwd-console %s %s REDACTED\"\n",
+		log.Printf("console name=\"%s\" dev=\"/usr/bin/ssh-pwd-console %s %s REDACTED\"%s\n",
 			nodeCi.NodeName,
 			nodeCi.BmcFqdn,
-			creds.Username)
+			creds.Username,
+			consoleLogOption(nodeCi))
 		// write the line to the config file
-		output := fmt.Sprintf("console name=\"%s\" dev=\"/usr/bin/ssh-pwd-console %s %s %s\"\n",
+		output := fmt.Sprintf("console name=\"%s\" dev=\"/usr/bin/ssh-pwd-console %s %s %s\"%s\n",
 			nodeCi.NodeName,
 			nodeCi.BmcFqdn,
 			creds.Username,
-			creds.Password)
+			creds.Password,
+			consoleLogOption(nodeCi))
 
 		// write the output line if there is anything present
 		if _, err = cf.WriteString(output); err != nil {
@@ -342,15 +390,24 @@ func updateConfigFile(forceUpdate bool) {
 
 	}
 
+	// remember the consoles waiting on creds
+	setPendingCredentials(pending)
+
 	// Add Mountain endpoints to the config file
 	for _, nodeCi := range currentMtnNodes {
-		log.Printf("console name=\"%s\" dev=\"/usr/bin/ssh-key-console %s\"\n",
+		if tenantPending(nodeCi.NodeName) {
+			log.Printf("No tenant yet for %s, leaving it out of the configuration", nodeCi.NodeName)
+			continue
+		}
+		log.Printf("console name=\"%s\" dev=\"/usr/bin/ssh-key-console %s\"%s\n",
 			nodeCi.NodeName,
-			nodeCi.NodeName)
+			nodeCi.NodeName,
+			consoleLogOption(nodeCi))
 		// write the line to the config file
-		output := fmt.Sprintf("console name=\"%s\" dev=\"/usr/bin/ssh-key-console %s\"\n",
+		output := fmt.Sprintf("console name=\"%s\" dev=\"/usr/bin/ssh-key-console %s\"%s\n",
+			nodeCi.NodeName,
 			nodeCi.NodeName,
-			nodeCi.NodeName)
+			consoleLogOption(nodeCi))
 
 		// write the output line if there is anything present
 		if _, err = cf.WriteString(output); err != nil {
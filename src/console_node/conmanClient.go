@@ -0,0 +1,72 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the use of the conman client to talk to a console
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Escape sequences understood by the conman client
+const (
+	conmanEscape      string = "&"
+	conmanEscapeClose string = "&."
+)
+
+// Time allowed for a conman client to deliver input
+var conmanClientTimeout time.Duration = 10 * time.Second
+
+// Escape console input so the conman client passes it through unchanged
+func escapeConmanInput(data string) string {
+	return strings.ReplaceAll(data, conmanEscape, conmanEscape+conmanEscape)
+}
+
+// Send raw input to a console through the conman client
+func sendConmanInput(xname, input string) error {
+	// NOTE: the input must already be escaped, it is passed to the client as is
+	//  with a close sequence on the end so the client exits when done.  Joining
+	//  the console keeps any interactive users connected.
+	ctx, cancel := context.WithTimeout(context.Background(), conmanClientTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "conman", "-j", "-Q", xname)
+	cmd.Stdin = strings.NewReader(input + conmanEscapeClose)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("conman client for %s failed: %s %s", xname, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Send text to a console
+func sendConsoleInput(xname, text string) error {
+	return sendConmanInput(xname, escapeConmanInput(text))
+}
@@ -0,0 +1,190 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the http api for requests against a single console
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Base path of all requests against a single console
+const consoleAPIBase string = "/console-node/consoles/"
+
+// Split the request path into the console xname and the operation
+func parseConsolePath(path string) (xname, op string, ok bool) {
+	// NOTE: expecting a path of the form /console-node/consoles/{xname}/{op}
+	if !strings.HasPrefix(path, consoleAPIBase) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(path[len(consoleAPIBase):], "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Look up a console that is currently being monitored by this pod
+func lookupConsole(xname string) (nodeConsoleInfo, bool) {
+	currNodesMutex.Lock()
+	defer currNodesMutex.Unlock()
+
+	allNodes := [3](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes, &currentMtnNodes}
+	for _, ar := range allNodes {
+		if ni, ok := (*ar)[xname]; ok {
+			return *ni, true
+		}
+	}
+	return nodeConsoleInfo{}, false
+}
+
+// ConsoleStatus - current state of a single console
+type ConsoleStatus struct {
+	Xname     string          `json:"xname"`
+	BmcName   string          `json:"bmc_name"`
+	Class     string          `json:"class"`
+	NID       int             `json:"nid"`
+	Role      string          `json:"role"`
+	Tenant    string          `json:"tenant,omitempty"`
+	Pod       string          `json:"pod"`
+	State     string          `json:"state"`
+	BootPhase string          `json:"boot_phase,omitempty"`
+	Health    *ConsoleHealth  `json:"health,omitempty"`
+	Identity  *IdentityStatus `json:"identity,omitempty"`
+}
+
+// Handle a request for the status of a single console
+func doConsoleStatus(w http.ResponseWriter, r *http.Request, node nodeConsoleInfo) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	cs := ConsoleStatus{
+		Xname:   node.NodeName,
+		BmcName: node.BmcName,
+		Class:   node.Class,
+		NID:     node.NID,
+		Role:    node.Role,
+		Tenant:  node.Tenant,
+		Pod:     podName,
+		State:   "configured",
+	}
+	if tenantPending(node.NodeName) {
+		cs.State = "pending tenant"
+	} else if credentialsPending(node.NodeName) {
+		cs.State = "pending credentials"
+	}
+	if bs, ok := getBootStatus(node.NodeName); ok && bs.Current != nil {
+		cs.BootPhase = bs.Current.Phase
+	}
+	if h, ok := getConsoleHealth(node.NodeName); ok {
+		cs.Health = &h
+	}
+	if id, ok := getIdentity(node.NodeName); ok {
+		cs.Identity = &id
+	}
+	SendResponseJSON(w, http.StatusOK, cs)
+}
+
+// Route requests for a single console to the correct handler
+func doConsoleRequest(w http.ResponseWriter, r *http.Request) {
+	xname, op, ok := parseConsolePath(r.URL.Path)
+	if !ok {
+		sendJSONError(w, http.StatusNotFound,
+			fmt.Sprintf("Unknown console request: %s", r.URL.Path))
+		return
+	}
+
+	// make sure the caller may use the console this way
+	if authEnabled {
+		claims, sc, err := authenticate(r, consoleOpRole(op, r.Method))
+		if err == nil && !claims.allowsXname(xname) {
+			sc, err = http.StatusForbidden, fmt.Errorf("access to console %s is not allowed", xname)
+		}
+		if err != nil {
+			auditDenied(r, xname, op, err)
+			sendAuthError(w, sc, err)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), authContextKey{}, claims))
+	} else if consoleOpRole(op, r.Method) == roleInteractive {
+		// without authentication the consoles are read-only to callers outside the pod
+		claims, sc, err := authorizeAnonymousWrite(r)
+		if err != nil {
+			auditDenied(r, xname, op, err)
+			sendAuthError(w, sc, err)
+			return
+		}
+		if claims != nil {
+			r = r.WithContext(context.WithValue(r.Context(), authContextKey{}, claims))
+		}
+	}
+
+	// only consoles monitored by this pod can be accessed here
+	// NOTE: looked up after the checks above so callers can not find out where
+	//  consoles they may not use are monitored
+	node, ok := lookupConsole(xname)
+	if !ok {
+		sendJSONError(w, http.StatusNotFound,
+			fmt.Sprintf("Console %s is not monitored by pod %s", xname, podName))
+		return
+	}
+
+	// every request against a console goes in the audit log
+	auditConsoleRequest(w, r, xname, op, func(w http.ResponseWriter) {
+		switch op {
+		case "status":
+			doConsoleStatus(w, r, node)
+		case "screen":
+			doConsoleScreen(w, r, xname)
+		case "recording":
+			doConsoleRecording(w, r, xname)
+		case "log":
+			doConsoleLog(w, r, xname)
+		case "hwerrors":
+			doConsoleHwErrors(w, r, xname)
+		case "boot":
+			doConsoleBoot(w, r, xname)
+		case "reconnect":
+			doConsoleReconnect(w, r, xname)
+		case "input":
+			doConsoleInput(w, r, xname)
+		case "automation":
+			doConsoleAutomation(w, r, xname)
+		case "power":
+			doConsolePower(w, r, xname)
+		default:
+			sendJSONError(w, http.StatusNotFound,
+				fmt.Sprintf("Unknown console operation: %s", op))
+		}
+	})
+}
@@ -0,0 +1,469 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the detection of silent and stuck consoles and the
+// reconnection of a single stuck console
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// States of a console connection
+const (
+	consoleHealthy string = "healthy"
+	consoleIdle    string = "idle"
+	consoleStuck   string = "stuck"
+)
+
+// Configuration of the console health checks
+var consoleIdleSecs int = 600                              // time without output before a console is idle
+var consoleStuckSecs int = 300                             // time disconnected before a console is stuck
+var consoleSilentStuckSecs int = 3600                      // time connected without output before a console is stuck, 0 never
+var consoleProbeEnabled bool = false                       // send a newline to idle consoles at a login prompt
+var consoleProbeTimeoutSecs int = 60                       // time to wait for a response to a probe
+var consoleAutoReconnect bool = true                       // reconnect stuck consoles automatically
+var consoleReconnectMinSecs int = 900                      // minimum time between reconnects of a console
+var consoleHealthInterval time.Duration = 30 * time.Second // time between health checks
+
+// Location of the pid file of conmand
+const conmandPidFile string = "/var/run/conman.pid"
+
+// ConsoleHealth - activity and connection state of a single console
+type ConsoleHealth struct {
+	State         string       `json:"state"`
+	StateSince    time.Time    `json:"state_since"`
+	Connected     bool         `json:"connected"`
+	LastOutput    time.Time    `json:"last_output,omitempty"`
+	Bytes         int64        `json:"bytes"`
+	Lines         int64        `json:"lines"`
+	LastProbe     time.Time    `json:"last_probe,omitempty"`
+	LastReconnect time.Time    `json:"last_reconnect,omitempty"`
+	Reconnects    int          `json:"reconnects"`
+	Flood         *FloodStatus `json:"flood,omitempty"`
+}
+
+// The activity tracked for a single console
+type consoleActivity struct {
+	ConsoleHealth
+	started         time.Time
+	connectedSince  time.Time
+	probePending    bool
+	disconnectCause string
+}
+
+// Globals holding the activity of each console
+var activityMutex = &sync.Mutex{}
+var consoleActivities map[string]*consoleActivity = make(map[string]*consoleActivity) // [xname,*consoleActivity]
+
+// Read the console health configuration from the environment
+func initConsoleHealth() {
+	readSingleEnvVarInt("CONSOLE_IDLE_SEC", &consoleIdleSecs, 30, 7*86400)
+	readSingleEnvVarInt("CONSOLE_STUCK_SEC", &consoleStuckSecs, 30, 86400)
+	readSingleEnvVarInt("CONSOLE_SILENT_STUCK_SEC", &consoleSilentStuckSecs, 0, 7*86400)
+	readSingleEnvVarInt("CONSOLE_PROBE_TIMEOUT_SEC", &consoleProbeTimeoutSecs, 5, 3600)
+	readSingleEnvVarInt("CONSOLE_RECONNECT_MIN_SEC", &consoleReconnectMinSecs, 60, 86400)
+	if val := os.Getenv("CONSOLE_PROBE_ENABLE"); val != "" {
+		consoleProbeEnabled = isTrue(val)
+	}
+	if val := os.Getenv("CONSOLE_AUTO_RECONNECT"); val != "" {
+		consoleAutoReconnect = isTrue(val)
+	}
+	log.Printf("Console health: idle after %ds, stuck after %ds disconnected or %ds silent, probe:%v, auto reconnect:%v",
+		consoleIdleSecs, consoleStuckSecs, consoleSilentStuckSecs, consoleProbeEnabled, consoleAutoReconnect)
+
+	go watchConsoleHealth()
+}
+
+// Start tracking the activity of a console
+func startConsoleActivity(xname string) {
+	activityMutex.Lock()
+	defer activityMutex.Unlock()
+	if _, ok := consoleActivities[xname]; !ok {
+		now := time.Now()
+		// NOTE: conman has usually connected before the log is tailed so
+		//  start out assuming the console is connected
+		ca := &consoleActivity{started: now, connectedSince: now}
+		ca.State = consoleHealthy
+		ca.StateSince = now
+		ca.Connected = true
+		consoleActivities[xname] = ca
+	}
+}
+
+// Stop tracking the activity of a console that is no longer monitored
+func removeConsoleActivity(xname string) {
+	activityMutex.Lock()
+	defer activityMutex.Unlock()
+	delete(consoleActivities, xname)
+}
+
+// Get the health of a console
+func getConsoleHealth(xname string) (ConsoleHealth, bool) {
+	activityMutex.Lock()
+	defer activityMutex.Unlock()
+	ca, ok := consoleActivities[xname]
+	if !ok {
+		return ConsoleHealth{}, false
+	}
+	h := ca.ConsoleHealth
+	if fs, ok := getFloodStatus(xname); ok {
+		h.Flood = &fs
+	}
+	return h, true
+}
+
+// Note a line of output on a console
+func noteConsoleActivity(xname, text string, when time.Time) {
+	if _, rest := splitConmanTimestamp(text); isConmanMessage(rest) {
+		text = rest
+	}
+
+	activityMutex.Lock()
+	defer activityMutex.Unlock()
+	ca, ok := consoleActivities[xname]
+	if !ok {
+		return
+	}
+
+	// messages from conman give the connection state but are not console output
+	if isConmanMessage(text) {
+		switch {
+		case strings.Contains(text, " disconnected "), strings.Contains(text, " exited "),
+			strings.Contains(text, " terminated "), strings.Contains(text, "Unable to "):
+			if ca.Connected {
+				ca.Connected = false
+				ca.connectedSince = when
+				ca.disconnectCause = strings.TrimSpace(strings.TrimPrefix(text, conmanMsgPrefix))
+			}
+		case strings.Contains(text, " connected to "):
+			if !ca.Connected {
+				ca.Connected = true
+				ca.connectedSince = when
+				ca.disconnectCause = ""
+			}
+		}
+		return
+	}
+
+	ca.LastOutput = when
+	ca.Bytes += int64(len(text)) + 1
+	ca.Lines++
+	ca.probePending = false
+}
+
+// Periodically classify the consoles and act on the stuck ones
+func watchConsoleHealth() {
+	for {
+		time.Sleep(consoleHealthInterval)
+		checkConsoleHealth(time.Now())
+	}
+}
+
+// Work out the state of each console and probe or reconnect as needed
+func checkConsoleHealth(now time.Time) {
+	var toProbe, toReconnect []string
+
+	activityMutex.Lock()
+	for xname, ca := range consoleActivities {
+		lastOut := ca.LastOutput
+		if lastOut.IsZero() {
+			lastOut = ca.started
+		}
+		idleFor := now.Sub(lastOut)
+		idleTime := time.Duration(consoleIdleSecs) * time.Second
+
+		// NOTE: a hung SOL session or dead ssh channel still looks connected, so
+		//  a console silent for long enough is stuck even without a probe, timed
+		//  from the last connect or reconnect so a quiet console is not reconnected
+		//  on every pass
+		silentSince := lastOut
+		for _, t := range []time.Time{ca.connectedSince, ca.LastReconnect} {
+			if t.After(silentSince) {
+				silentSince = t
+			}
+		}
+
+		state := consoleHealthy
+		reason := ""
+		switch {
+		case !ca.Connected && now.Sub(ca.connectedSince) > time.Duration(consoleStuckSecs)*time.Second:
+			state = consoleStuck
+			reason = fmt.Sprintf("disconnected since %s: %s", ca.connectedSince.Format(time.RFC3339), ca.disconnectCause)
+		case idleFor < idleTime:
+			state = consoleHealthy
+		case ca.probePending && now.Sub(ca.LastProbe) > time.Duration(consoleProbeTimeoutSecs)*time.Second:
+			state = consoleStuck
+			reason = fmt.Sprintf("no response to probe sent at %s", ca.LastProbe.Format(time.RFC3339))
+		case ca.Connected && consoleSilentStuckSecs > 0 && now.Sub(silentSince) > time.Duration(consoleSilentStuckSecs)*time.Second:
+			state = consoleStuck
+			reason = fmt.Sprintf("connected but silent since %s", silentSince.Format(time.RFC3339))
+		default:
+			state = consoleIdle
+			if consoleProbeEnabled && !ca.probePending && ca.Connected && atLoginPrompt(xname) {
+				ca.probePending = true
+				ca.LastProbe = now
+				toProbe = append(toProbe, xname)
+			}
+		}
+
+		if state != ca.State {
+			if state == consoleStuck {
+				raiseEvent(xname, "console_stuck", eventWarning,
+					fmt.Sprintf("console %s is stuck, %s", xname, reason), nil)
+			} else if ca.State == consoleStuck {
+				raiseEvent(xname, "console_recovered", eventInfo,
+					fmt.Sprintf("console %s is %s again", xname, state), nil)
+			}
+			ca.State = state
+			ca.StateSince = now
+		}
+
+		if state == consoleStuck && consoleAutoReconnect &&
+			now.Sub(ca.LastReconnect) > time.Duration(consoleReconnectMinSecs)*time.Second {
+			ca.LastReconnect = now
+			ca.Reconnects++
+			ca.probePending = false
+			toReconnect = append(toReconnect, xname)
+		}
+	}
+	activityMutex.Unlock()
+
+	// talk to the consoles outside of the lock
+	for _, xname := range toProbe {
+		if err := sendConsoleInput(xname, "\r"); err != nil {
+			log.Printf("Unable to probe console %s: %s", xname, err)
+		}
+	}
+	for _, xname := range toReconnect {
+		if err := reconnectConsole(xname); err != nil {
+			log.Printf("Unable to reconnect console %s: %s", xname, err)
+		}
+	}
+}
+
+// See if a console is sitting at a login prompt where a newline is harmless
+func atLoginPrompt(xname string) bool {
+	// NOTE: the prompt is not followed by a newline so look for it in the
+	//  unfinished line at the end of the log file
+	last, _ := readPartialLine(consoleLogFile(xname), 0)
+	_, last = splitConmanTimestamp(strings.TrimRight(last, "\r"))
+	return loginHostRe.MatchString(last)
+}
+
+// Reconnect a single console without restarting conmand
+func reconnectConsole(xname string) error {
+	node, ok := lookupConsole(xname)
+	if !ok {
+		return fmt.Errorf("console %s is not monitored by this pod", xname)
+	}
+
+	var err error
+	if node.isRiver() {
+		err = deactivateSOL(node)
+	} else {
+		// process based consoles are restarted by conmand when they exit
+		target := node.NodeName
+		if node.isParadise() {
+			target = node.BmcFqdn
+		}
+		err = killConsoleProcess(target)
+	}
+	if err != nil {
+		raiseEvent(xname, "console_reconnect_failed", eventWarning,
+			fmt.Sprintf("reconnect of console %s failed: %s", xname, err), nil)
+		return err
+	}
+	raiseEvent(xname, "console_reconnect", eventInfo,
+		fmt.Sprintf("reconnecting console %s", xname), nil)
+	return nil
+}
+
+// Drop the SOL session of an ipmi console so conmand opens a new one
+func deactivateSOL(node nodeConsoleInfo) error {
+	currNodesMutex.Lock()
+	creds, ok := previousPasswords[node.BmcName]
+	currNodesMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("no credentials for %s", node.BmcName)
+	}
+
+	// the credentials go through a private config file, not the command line
+	// where every process in the pod could read them
+	confFile, err := writeIpmiConfig(creds.Username, creds.Password)
+	if err != nil {
+		return fmt.Errorf("unable to write the ipmiconsole config: %s", err)
+	}
+	defer os.Remove(confFile)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ipmiconsole", "--config-file="+confFile, "-h", node.BmcFqdn, "--deactivate")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ipmiconsole deactivate failed: %s %s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+// Write a freeipmi config file holding the bmc credentials, readable only by this process
+func writeIpmiConfig(username, password string) (string, error) {
+	f, err := os.CreateTemp("", "ipmiconsole-*.conf")
+	if err != nil {
+		return "", err
+	}
+	// NOTE: CreateTemp makes the file 0600
+	_, err = fmt.Fprintf(f, "username %s\npassword %s\n", ipmiConfigValue(username), ipmiConfigValue(password))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Quote a freeipmi config value when it holds spaces or special characters
+func ipmiConfigValue(val string) string {
+	if !strings.ContainsAny(val, " \t\"#\\") {
+		return val
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val) + `"`
+}
+
+// Find the pid of the running conmand
+func conmandPid() (int, error) {
+	data, err := os.ReadFile(conmandPidFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// Stop the conmand child process serving a console so it is restarted
+func killConsoleProcess(target string) error {
+	ppid, err := conmandPid()
+	if err != nil {
+		return fmt.Errorf("unable to find conmand: %s", err)
+	}
+
+	statFiles, _ := filepath.Glob("/proc/[0-9]*/stat")
+	for _, sf := range statFiles {
+		// NOTE: the parent pid follows the state after the command name
+		stat, err := os.ReadFile(sf)
+		if err != nil {
+			continue
+		}
+		pos := bytes.LastIndexByte(stat, ')')
+		if pos < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[pos+1:]))
+		if len(fields) < 2 || fields[1] != strconv.Itoa(ppid) {
+			continue
+		}
+
+		// look for the console target in the arguments of the process
+		dir := filepath.Dir(sf)
+		cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+		if err != nil {
+			continue
+		}
+		for _, arg := range strings.Split(string(cmdline), "\x00") {
+			if arg == target {
+				pid, _ := strconv.Atoi(filepath.Base(dir))
+				log.Printf("Stopping console process %d for %s", pid, target)
+				return syscall.Kill(pid, syscall.SIGTERM)
+			}
+		}
+	}
+	return fmt.Errorf("no console process found for %s", target)
+}
+
+// Handle a request to reconnect a single console
+func doConsoleReconnect(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'POST' calls
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	activityMutex.Lock()
+	if ca, ok := consoleActivities[xname]; ok {
+		ca.LastReconnect = time.Now()
+		ca.Reconnects++
+		ca.probePending = false
+	}
+	activityMutex.Unlock()
+
+	if err := reconnectConsole(xname); err != nil {
+		sendJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Write out the console health metrics
+func writeConsoleHealthMetrics(mw *metricsWriter) {
+	activityMutex.Lock()
+	defer activityMutex.Unlock()
+
+	xnames := make([]string, 0, len(consoleActivities))
+	for xname := range consoleActivities {
+		xnames = append(xnames, xname)
+	}
+	sort.Strings(xnames)
+
+	mw.header("console_node_console_state", "gauge", "Health state of the console, 1 for the current state")
+	for _, xname := range xnames {
+		mw.value("console_node_console_state", 1, "xname", xname, "state", consoleActivities[xname].State)
+	}
+	mw.header("console_node_console_bytes_total", "counter", "Bytes of output received from the console")
+	for _, xname := range xnames {
+		mw.value("console_node_console_bytes_total", float64(consoleActivities[xname].Bytes), "xname", xname)
+	}
+	mw.header("console_node_console_reconnects_total", "counter", "Reconnects of the console")
+	for _, xname := range xnames {
+		mw.value("console_node_console_reconnects_total", float64(consoleActivities[xname].Reconnects), "xname", xname)
+	}
+}
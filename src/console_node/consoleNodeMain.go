@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2021-2024 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2021-2024, 2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -58,6 +58,9 @@ var inShutdown bool = false
 // global pointer to the active OperatorService
 var opService OperatorService = nil
 
+// global pointer to the active DataService
+var dataService DataService = nil
+
 // identify what the name of this pod is
 func setPodName() {
 	// The pod name is set as an env variable by the k8s system on pod
@@ -66,6 +69,10 @@ func setPodName() {
 	if val := os.Getenv("MY_POD_NAME"); val != "" {
 		podName = val
 		log.Printf("Pod name found: %s", podName)
+	} else if standaloneMode() {
+		// keep the same aggregation log across restarts when running alone
+		podName = "cray-console-node-standalone"
+		log.Printf("Pod name not set in env - using %s", podName)
 	} else {
 		// not found, so as stopgap make random number > 1000
 		rand.Seed(time.Now().UnixNano())
@@ -119,53 +126,130 @@ func main() {
 
 	// parse the command line flags to the application
 	flag.BoolVar(&debugOnly, "debug", false, "Run in debug only mode, not starting conmand")
+	flag.StringVar(&inventoryFile, "inventory", "", "Run standalone with the consoles listed in this file")
+	flag.BoolVar(&simulating, "simulate", false, "Run against simulated services and consoles")
 	flag.Parse()
 
 	// grab env vars
 	if v := os.Getenv("DEBUG"); v == "TRUE" {
 		debugOnly = true
 	}
+	if v := os.Getenv("STANDALONE_INVENTORY"); v != "" && inventoryFile == "" {
+		log.Printf("Found STANDALONE_INVENTORY: %s", v)
+		inventoryFile = v
+	}
+	if v := os.Getenv("SIMULATION"); v != "" && !simulating {
+		log.Printf("Found SIMULATION: %s", v)
+		simulating = isTrue(v)
+	}
+	if simulating && inventoryFile != "" {
+		log.Printf("Running the simulation, ignoring the inventory %s", inventoryFile)
+		inventoryFile = ""
+	}
+	if v := os.Getenv("AGG_LOG_TIMESTAMPS"); v != "" {
+		log.Printf("Found AGG_LOG_TIMESTAMPS: %s", v)
+		aggLogTimestamps = isTrue(v)
+	}
 	readSingleEnvVarInt("HEARTBEAT_SEND_FREQ_SEC", &heartbeatIntervalSecs, 5, 300)
 	readSingleEnvVarInt("NODE_UPDATE_FREQ_SEC", &newNodeLookupSec, 10, 600)
 	readSingleEnvVarInt("MAX_ACQUIRE_PER_UPDATE_MTN", &maxAcquireMtn, 5, 2000)
 	readSingleEnvVarInt("MAX_ACQUIRE_PER_UPDATE_RVR", &maxAcquireRvr, 5, 4000)
+	readSingleEnvVarInt("CONSOLE_SCREEN_ROWS", &screenRows, 10, 200)
+	readSingleEnvVarInt("CONSOLE_SCREEN_COLS", &screenCols, 40, 400)
 
 	// log the fact if we are in debug mode
 	if debugOnly {
 		log.Print("Running in DEBUG-ONLY mode.")
 	}
 
-	// do a quick check for creating needed directories
-	// NOTE: should probably be moved somewhere else, but want it really early in the
-	//  process for now...
-	ensureDirPresent("/var/log/conman", 666)
+	// set up the ownership policy of the log files before anything creates them
+	initLogPolicy()
+	ensureDirPresent(consoleLogDir, logDirMode)
+	startLogPolicyWatcher()
+	enforceLogPolicy()
 
 	// identify this pod
 	log.Printf("Setting pod information...")
 	setPodName()
 
 	// Construct services
-	opService = NewOperatorService()
+	// NOTE: the simulation has local stand-ins for the services outside of this pod
+	if simulationMode() {
+		startSimulation()
+	} else {
+		opService = NewOperatorService()
+		dataService = NewDataService()
+		vaultSession = NewVaultSession()
+	}
+	powerService = NewPowerService()
+	tenantService = NewTenantService()
+	initCredentials()
+	initMountainAuth()
 
 	// Find pod location in k8s, this must block and retry
-	setPodLocation(opService)
+	// NOTE: there is no console-operator to ask when running standalone
+	if !standaloneMode() {
+		setPodLocation(opService)
+	}
 
 	// start the aggregation log
+	initAggWriter()
 	respinAggLog()
 
 	// Initialize and start log rotation
 	logRotate()
 
+	// Set up the time indexes and recording of the console sessions
+	initTimeIndexes()
+	initRecordings()
+
+	// Set up the events raised about the consoles
+	initEvents()
+
+	// Set up the audit log of the console sessions
+	initAudit()
+
+	// Set up the authentication of the http api
+	initAuth()
+
+	// Set up searching of the console logs
+	initSearch()
+
+	// Set up tracking of hardware errors seen on the consoles
+	initHwErrors()
+
+	// Set up tracking of the boot progress of the nodes
+	initBootProgress()
+
+	// Set up verification of the node identity on the consoles
+	initIdentity()
+
+	// Set up detection of silent and stuck consoles
+	initConsoleHealth()
+
+	// Set up the output budgets of the consoles
+	initFloodProtection()
+
+	// Set up the automation of the consoles
+	initAutomation()
+
 	// Set up the zombie killer
 	log.Printf("Starting zombie killer...")
 	go watchForZombies()
 
 	// spin a thread that watches for changes in console configuration
-	log.Printf("Starting hardware watch loop...")
-	go watchForNodes()
+	// NOTE: when standalone the consoles come from the inventory file and
+	//  there is no console-data to send heartbeats to
+	if standaloneMode() {
+		log.Printf("Starting inventory watch loop...")
+		go watchInventory()
+	} else {
+		log.Printf("Starting hardware watch loop...")
+		go watchForNodes()
 
-	// start up the heartbeat in a separate thread
-	go doHeartbeat()
+		// start up the heartbeat in a separate thread
+		go doHeartbeat()
+	}
 
 	// start up the thread that runs conman
 	go runConman()
@@ -192,6 +276,13 @@ func main() {
 	http.HandleFunc("/console-node/liveness", doLiveness)
 	http.HandleFunc("/console-node/readiness", doReadiness)
 	http.HandleFunc("/console-node/health", doHealth)
+	http.HandleFunc(consoleAPIBase, doConsoleRequest)
+	http.HandleFunc("/console-node/search", requireRole(roleLogs, doSearch))
+	http.HandleFunc("/console-node/hwerrors", requireRole(roleLogs, doHwErrors))
+	http.HandleFunc("/console-node/boots", requireRole(roleLogs, doBoots))
+	http.HandleFunc("/console-node/metrics", requireRole(roleHealth, doMetrics))
+	http.HandleFunc("/console-node/events", requireRole(roleLogs, doEvents))
+	http.HandleFunc("/console-node/audit", requireRole(roleAdmin, doAudit))
 
 	// spin the server in a separate thread so main can wait on an os
 	// signal to cleanly shut down
@@ -0,0 +1,458 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the providers of the BMC credentials
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	compcreds "github.com/Cray-HPE/hms-compcredentials"
+	"gopkg.in/yaml.v3"
+)
+
+// Error for a BMC the provider has no credentials for
+var errCredsNotFound = errors.New("no credentials found")
+
+// Result of looking up the credentials of a single BMC
+type credResult struct {
+	creds compcreds.CompCredentials
+	err   error
+}
+
+// CredentialProvider - interface for looking up the credentials of the BMCs
+type CredentialProvider interface {
+	lookupCredentials(bmcXNames []string) map[string]credResult
+	Name() string
+}
+
+// NewCredentialProvider creates the credential provider selected by the environment
+func NewCredentialProvider() CredentialProvider {
+	provider := strings.ToLower(os.Getenv("CREDS_PROVIDER"))
+	if provider == "" && simulationMode() {
+		provider = "sim"
+	}
+	switch provider {
+	case "sim":
+		log.Printf("Using the simulation for BMC credentials")
+		return &SimCredManager{cluster: simulation}
+	case "secret":
+		dir := os.Getenv("CREDS_SECRET_DIR")
+		if dir == "" {
+			dir = "/etc/console-node/bmc-creds"
+		}
+		log.Printf("Using the mounted secret %s for BMC credentials", dir)
+		return &SecretDirCredManager{dir: dir}
+	case "file":
+		fn := os.Getenv("CREDS_FILE")
+		log.Printf("Using %s for BMC credentials", fn)
+		return &CredFileManager{fileName: fn}
+	}
+	mount := strings.Trim(os.Getenv("CREDS_VAULT_MOUNT"), "/")
+	if mount == "" {
+		mount = "secret"
+	}
+	keyPath := os.Getenv("CREDS_VAULT_PATH")
+	if keyPath == "" {
+		keyPath = "hms-creds"
+	}
+	log.Printf("Using vault %s/%s for BMC credentials", mount, keyPath)
+	return &VaultCredManager{session: NewCredsVaultSession(), mount: mount, keyPath: keyPath}
+}
+
+// VaultCredManager - credentials from the hms compcreds store in vault
+type VaultCredManager struct {
+	session *VaultSession
+	mount   string
+	keyPath string
+}
+
+// Name of the provider
+func (vm *VaultCredManager) Name() string {
+	return "vault"
+}
+
+// Look up the credentials of the BMCs in vault
+func (vm *VaultCredManager) lookupCredentials(bmcXNames []string) map[string]credResult {
+	results := make(map[string]credResult)
+	ccs := compcreds.NewCompCredStore(vm.keyPath, &VaultSecretStore{session: vm.session, basePath: vm.mount})
+	for _, bmc := range bmcXNames {
+		creds, err := ccs.GetCompCred(bmc)
+		if err == nil && creds.Username == "" && creds.Password == "" {
+			err = errCredsNotFound
+		}
+		results[bmc] = credResult{creds: creds, err: err}
+	}
+	return results
+}
+
+// SecretDirCredManager - credentials from a mounted secret with a file per BMC
+// NOTE: each file holds {"username":"..","password":".."}, a file named
+// 'default' is used for BMCs without their own file
+type SecretDirCredManager struct {
+	dir string
+}
+
+// Name of the provider
+func (sm *SecretDirCredManager) Name() string {
+	return "secret"
+}
+
+// Read the credentials of a single BMC from the secret directory
+func (sm *SecretDirCredManager) readCreds(name string) (compcreds.CompCredentials, error) {
+	var creds compcreds.CompCredentials
+	data, err := os.ReadFile(filepath.Join(sm.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return creds, errCredsNotFound
+		}
+		return creds, err
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("invalid credentials in %s: %s", name, err)
+	}
+	return creds, nil
+}
+
+// Look up the credentials of the BMCs in the secret directory
+func (sm *SecretDirCredManager) lookupCredentials(bmcXNames []string) map[string]credResult {
+	results := make(map[string]credResult)
+	def, defErr := sm.readCreds("default")
+	for _, bmc := range bmcXNames {
+		creds, err := sm.readCreds(filepath.Base(bmc))
+		if err == errCredsNotFound && defErr == nil {
+			creds, err = def, nil
+		}
+		creds.Xname = bmc
+		results[bmc] = credResult{creds: creds, err: err}
+	}
+	return results
+}
+
+// CredFileManager - credentials from a static JSON or YAML file for labs
+// NOTE: the file maps BMC xnames, or 'default', to a username and password
+type CredFileManager struct {
+	fileName string
+}
+
+// Name of the provider
+func (fm *CredFileManager) Name() string {
+	return "file"
+}
+
+// Look up the credentials of the BMCs in the file
+func (fm *CredFileManager) lookupCredentials(bmcXNames []string) map[string]credResult {
+	results := make(map[string]credResult)
+	all, err := readCredsFile(fm.fileName)
+	for _, bmc := range bmcXNames {
+		if err != nil {
+			results[bmc] = credResult{err: err}
+			continue
+		}
+		creds, ok := all[bmc]
+		if !ok {
+			creds, ok = all["default"]
+		}
+		if !ok {
+			results[bmc] = credResult{err: errCredsNotFound}
+			continue
+		}
+		creds.Xname = bmc
+		results[bmc] = credResult{creds: creds}
+	}
+	return results
+}
+
+// Read a credentials file
+func readCredsFile(fileName string) (map[string]compcreds.CompCredentials, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	all, err := parseCredsFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %s", fileName, err)
+	}
+	return all, nil
+}
+
+// Credentials of a BMC as written in a YAML credentials file
+type credsFileEntry struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Parse a credentials file, JSON or YAML mapping BMC xnames to a username and password
+func parseCredsFile(data []byte) (map[string]compcreds.CompCredentials, error) {
+	all := make(map[string]compcreds.CompCredentials)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &all); err != nil {
+			return nil, err
+		}
+		for bmc, creds := range all {
+			creds.Xname = bmc
+			all[bmc] = creds
+		}
+		return all, nil
+	}
+
+	var entries map[string]credsFileEntry
+	if err := decodeYaml(data, &entries); err != nil {
+		return nil, err
+	}
+	for bmc, e := range entries {
+		all[bmc] = compcreds.CompCredentials{Xname: bmc, Username: e.Username, Password: e.Password}
+	}
+	return all, nil
+}
+
+// Decode a YAML document, rejecting keys the value has no field for
+func decodeYaml(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// Provider used to look up the BMC credentials
+var credProvider CredentialProvider = nil
+
+// Time credentials found are used before asking the provider again
+var credsCacheTTLSecs int = 300
+
+// Cached credentials of each BMC
+type credCacheEntry struct {
+	result  credResult
+	fetched time.Time // last time the provider was asked
+	found   time.Time // last time the provider had the credentials
+}
+
+var credCacheMutex = &sync.Mutex{}
+var credCache map[string]*credCacheEntry = make(map[string]*credCacheEntry) // [bmc,entry]
+
+// Set up the credential provider
+func initCredentials() {
+	readSingleEnvVarInt("CREDS_CACHE_TTL_SEC", &credsCacheTTLSecs, 0, 86400)
+	readSingleEnvVarInt("CREDS_RETRY_SEC", &credsRetrySecs, 1, 3600)
+	credProvider = NewCredentialProvider()
+	go watchPendingCredentials()
+}
+
+// State of the vault login used for the credentials, nil if vault is not used
+func credsVaultStatus() *VaultAuthStatus {
+	vm, ok := credProvider.(*VaultCredManager)
+	if !ok || vm.session == vaultSession {
+		return nil
+	}
+	st := vm.session.status()
+	return &st
+}
+
+// Look up the credentials of the BMCs, using cached ones that are still fresh
+// NOTE: credentials that were found before are kept if the provider fails, so a
+// provider outage does not take down consoles that are already configured
+func resolveCredentials(bmcXNames []string, refresh bool) map[string]credResult {
+	now := time.Now()
+	ttl := time.Duration(credsCacheTTLSecs) * time.Second
+
+	// find what needs asking for
+	var stale []string
+	credCacheMutex.Lock()
+	for _, bmc := range bmcXNames {
+		ce, ok := credCache[bmc]
+		if refresh || !ok || ce.result.err != nil || now.Sub(ce.fetched) >= ttl {
+			stale = append(stale, bmc)
+		}
+	}
+	credCacheMutex.Unlock()
+
+	// ask the provider without holding the lock
+	var fresh map[string]credResult
+	if len(stale) > 0 {
+		fresh = credProvider.lookupCredentials(stale)
+	}
+
+	credCacheMutex.Lock()
+	defer credCacheMutex.Unlock()
+	results := make(map[string]credResult)
+	for _, bmc := range bmcXNames {
+		ce, ok := credCache[bmc]
+		if !ok {
+			ce = &credCacheEntry{}
+			credCache[bmc] = ce
+		}
+		if r, asked := fresh[bmc]; asked {
+			ce.fetched = now
+			if r.err == nil {
+				ce.result = r
+				ce.found = now
+			} else if ce.found.IsZero() || r.err == errCredsNotFound {
+				ce.result = r
+			} else {
+				log.Printf("Unable to refresh the credentials of %s, keeping the previous ones: %s", bmc, r.err)
+			}
+		}
+		results[bmc] = ce.result
+	}
+	return results
+}
+
+// Forget the cached credentials of BMCs that are no longer monitored
+func pruneCredentials(bmcXNames []string) {
+	keep := make(map[string]bool)
+	for _, bmc := range bmcXNames {
+		keep[bmc] = true
+	}
+	credCacheMutex.Lock()
+	defer credCacheMutex.Unlock()
+	for bmc := range credCache {
+		if !keep[bmc] {
+			delete(credCache, bmc)
+		}
+	}
+}
+
+// Credentials already found for the BMCs, without asking the provider
+func cachedPasswords(bmcXNames []string) map[string]compcreds.CompCredentials {
+	credCacheMutex.Lock()
+	defer credCacheMutex.Unlock()
+	passwords := make(map[string]compcreds.CompCredentials)
+	for _, bmc := range bmcXNames {
+		if ce, ok := credCache[bmc]; ok && ce.result.err == nil {
+			passwords[bmc] = ce.result.creds
+		}
+	}
+	return passwords
+}
+
+// Look up the credentials of the current consoles outside of the node lock
+func prefetchCredentials() {
+	// NOTE: in update config thread
+	if bmcs := getCurrCredBmcXnames(); len(bmcs) > 0 {
+		getPasswords(bmcs, false)
+	}
+}
+
+// Time between looking for the credentials of pending consoles
+var credsRetrySecs int = 10
+
+// Consoles left out of the conman configuration until their credentials are found
+var credsPendingMutex = &sync.Mutex{}
+var credsPending map[string]string = make(map[string]string) // [xname,bmc]
+
+// Record the consoles waiting on credentials
+func setPendingCredentials(pending map[string]string) {
+	// NOTE: in update config thread
+	credsPendingMutex.Lock()
+	defer credsPendingMutex.Unlock()
+	for xname, bmc := range pending {
+		if _, ok := credsPending[xname]; !ok {
+			raiseEvent(xname, "credentials_pending", eventWarning,
+				fmt.Sprintf("no credentials for %s, console %s is not connected", bmc, xname),
+				map[string]string{"bmc": bmc})
+		}
+	}
+	credsPending = pending
+}
+
+// Check if a console is waiting on credentials
+func credentialsPending(xname string) bool {
+	credsPendingMutex.Lock()
+	defer credsPendingMutex.Unlock()
+	_, ok := credsPending[xname]
+	return ok
+}
+
+// Number of consoles waiting on credentials
+func numPendingCredentials() int {
+	credsPendingMutex.Lock()
+	defer credsPendingMutex.Unlock()
+	return len(credsPending)
+}
+
+// Sorted names of the consoles waiting on credentials
+func pendingCredentialConsoles() []string {
+	credsPendingMutex.Lock()
+	defer credsPendingMutex.Unlock()
+	var xnames []string
+	for xname := range credsPending {
+		xnames = append(xnames, xname)
+	}
+	sort.Strings(xnames)
+	return xnames
+}
+
+// Keep looking for the credentials of pending consoles and add them once found
+func watchPendingCredentials() {
+	for {
+		time.Sleep(time.Duration(credsRetrySecs) * time.Second)
+
+		credsPendingMutex.Lock()
+		var bmcs []string
+		for _, bmc := range credsPending {
+			bmcs = append(bmcs, bmc)
+		}
+		credsPendingMutex.Unlock()
+		if len(bmcs) == 0 {
+			continue
+		}
+
+		// take the consoles that now have credentials out of the pending list
+		found := getPasswords(bmcs, false)
+		var ready []string
+		credsPendingMutex.Lock()
+		for xname, bmc := range credsPending {
+			if _, ok := found[bmc]; ok {
+				ready = append(ready, xname)
+				delete(credsPending, xname)
+			}
+		}
+		credsPendingMutex.Unlock()
+		if len(ready) == 0 {
+			continue
+		}
+
+		// the configuration is rebuilt with them when conman restarts
+		for _, xname := range ready {
+			raiseEvent(xname, "credentials_found", eventInfo,
+				fmt.Sprintf("credentials found, adding console %s", xname), nil)
+		}
+		log.Printf("Credentials found for %d pending consoles, reconfiguring conman", len(ready))
+		signalConmanTERM()
+	}
+}
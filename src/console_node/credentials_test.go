@@ -0,0 +1,125 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the tests of reading the credentials file
+
+package main
+
+import "testing"
+
+func TestParseCredsFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		bmc      string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{
+			name:     "yaml",
+			data:     "x3000c0s1b0:\n  username: root\n  password: initial0\n",
+			bmc:      "x3000c0s1b0",
+			username: "root",
+			password: "initial0",
+		},
+		{
+			name:     "yaml quoted hash",
+			data:     "x3000c0s1b0:\n  username: root\n  password: \"abc #1\"\n",
+			bmc:      "x3000c0s1b0",
+			username: "root",
+			password: "abc #1",
+		},
+		{
+			name:     "yaml comments",
+			data:     "# lab bmcs\n---\ndefault: # fallback\n  username: admin # the user\n  password: 'x#y'\n",
+			bmc:      "default",
+			username: "admin",
+			password: "x#y",
+		},
+		{
+			name:     "yaml quoted key",
+			data:     "\"x3000c0s1b0\":\n  username: root\n  password: \"with: colon\"\n",
+			bmc:      "x3000c0s1b0",
+			username: "root",
+			password: "with: colon",
+		},
+		{
+			name:     "json",
+			data:     `{"x3000c0s1b0": {"username": "root", "password": "abc #1"}}`,
+			bmc:      "x3000c0s1b0",
+			username: "root",
+			password: "abc #1",
+		},
+		{
+			name:    "unknown key",
+			data:    "x3000c0s1b0:\n  user: root\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a mapping",
+			data:    "- root\n- secret\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad json",
+			data:    `{"x3000c0s1b0": `,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := parseCredsFile([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", all)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			creds, ok := all[tt.bmc]
+			if !ok {
+				t.Fatalf("no credentials for %s", tt.bmc)
+			}
+			if creds.Username != tt.username || creds.Password != tt.password {
+				t.Errorf("got %q/%q, want %q/%q", creds.Username, creds.Password, tt.username, tt.password)
+			}
+			if creds.Xname != tt.bmc {
+				t.Errorf("got xname %q, want %q", creds.Xname, tt.bmc)
+			}
+		})
+	}
+}
+
+func TestParseCredsFileEmpty(t *testing.T) {
+	all, err := parseCredsFile([]byte("# nothing yet\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(all) != 0 {
+		t.Errorf("expected no credentials, got %d", len(all))
+	}
+}
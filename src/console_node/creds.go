@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2020-2022, 2024 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2020-2022, 2024, 2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -29,17 +29,19 @@ package main
 import (
 	"bytes"
 	"crypto/sha256"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/ssh"
 
 	compcreds "github.com/Cray-HPE/hms-compcredentials"
-	sstorage "github.com/Cray-HPE/hms-securestorage"
 )
 
 // Location of the Mountain BMC console ssh key pair files.
@@ -51,103 +53,56 @@ const mountainConsoleKeyPub string = "/app/conman.key.pub"
 // to Vault.  This is part of the pod deployment.
 const svcAcctTokenFile string = "/var/run/secrets/kubernetes.io/serviceaccount/token"
 
-// Look up the creds for the input endpoints with retries
-func getPasswordsWithRetries(bmcXNames []string, maxTries, waitSecs int) map[string]compcreds.CompCredentials {
-	// NOTE: in update config thread
-
-	var passwords map[string]compcreds.CompCredentials = nil
-	for numTries := 0; numTries < maxTries; numTries++ {
-		log.Printf("Get passwords with retry: %d", numTries)
-		// get passwords from vault
-		passwords = getPasswords(bmcXNames)
-
-		// make sure we have something for all entries
-		foundAll := true
-		for _, nn := range bmcXNames {
-			_, ok := passwords[nn]
-			if !ok {
-				log.Printf("Missing credentials for %s", nn)
-				foundAll = false
-			}
-		}
-
-		// if we got all the passwords we are done
-		if foundAll {
-			log.Printf("Retrieved all passwords")
-			return passwords
-		}
-
-		// if we did not get all passwords try again until maxAttempts
-		log.Printf("Attempt %d - Only retrieved %d of %d River creds from vault, waiting and trying again...",
-			numTries, len(passwords), len(bmcXNames))
-		time.Sleep(time.Duration(waitSecs) * time.Second)
-	}
-
-	// We have reached max attempts, bail with what we have
-	log.Printf("Maximum password attempts reached, configuring conman with what we have.")
-	return passwords
-}
+// Namespace of the pod, the vault role used for the BMC credentials
+const svcAcctNamespaceFile string = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 
 // Look up the creds for the input endpoints
-func getPasswords(bmcXNames []string) map[string]compcreds.CompCredentials {
+func getPasswords(bmcXNames []string, refresh bool) map[string]compcreds.CompCredentials {
 	// NOTE: in update config thread
 
-	// if running in debug mode, skip hsm query
-	if debugOnly {
+	// if running in debug mode, skip the vault query
+	if debugOnly && credProvider.Name() == "vault" {
 		log.Print("DEBUGONLY mode - skipping creds query")
 		return nil
 	}
 
-	// Get the passwords from Hashicorp Vault
-	log.Print("Gathering creds from vault")
-
-	// Create the Vault adapter and connect to Vault
-	ss, err := sstorage.NewVaultAdapter("secret")
-	if err != nil {
-		log.Panicf("Error: %#v\n", err)
-	}
-
-	// Initialize the CompCredStore struct with the Vault adapter.
-	ccs := compcreds.NewCompCredStore("hms-creds", ss)
-
-	// Read the credentials for a list of components from the CompCredStore
-	// (backed by Vault).
-	ccreds, err := ccs.GetCompCreds(bmcXNames)
-	if err != nil {
-		log.Panicf("Error: %#v\n", err)
+	log.Printf("Gathering creds from %s", credProvider.Name())
+	passwords := make(map[string]compcreds.CompCredentials)
+	for bmc, res := range resolveCredentials(bmcXNames, refresh) {
+		if res.err != nil {
+			log.Printf("Unable to get the creds for %s: %s", bmc, res.err)
+			continue
+		}
+		passwords[bmc] = res.creds
 	}
-
-	return ccreds
+	return passwords
 }
 
 // Ask vault for the private key
-func vaultExportPrivateKey(vaultToken string) (pvtKey string, response []byte, responseCode int, err error) {
-	// get the vault base URL
-	vaultBase := os.Getenv("VAULT_URL")
-	if len(vaultBase) == 0 {
-		log.Printf("Error: VAULT_URL environment variable is not set, defaulting to http://cray-vault.vault:8200/v1")
-		vaultBase = "http://cray-vault.vault:8200/v1"
-	}
-
+func vaultExportPrivateKey() (pvtKey string, response []byte, responseCode int, err error) {
 	// get the name of the vault secret
 	vaultBmcKeyName := os.Getenv("VAULT_BMC_KEY_NAME")
 	if len(vaultBmcKeyName) == 0 {
-		log.Printf("Error: VAULT_BMC_KEY_NAME environment variable is not set, defaulting to bmc-console-key")
+		log.Printf("VAULT_BMC_KEY_NAME environment variable is not set, defaulting to mountain-bmc-console")
 		vaultBmcKeyName = "mountain-bmc-console"
 	}
 
+	// get the mount of the transit secrets engine holding the key
+	vaultTransitPath := strings.Trim(os.Getenv("VAULT_TRANSIT_PATH"), "/")
+	if len(vaultTransitPath) == 0 {
+		vaultTransitPath = "transit"
+	}
+
 	// query vault for the private key
-	URL := vaultBase + "/transit/export/signing-key/" + vaultBmcKeyName
-	vaultRequestHeaders := make(map[string]string)
-	vaultRequestHeaders["X-Vault-Token"] = vaultToken
-	response, responseCode, err = getURL(URL, vaultRequestHeaders)
+	response, responseCode, err = vaultSession.request(http.MethodGet,
+		"/"+vaultTransitPath+"/export/signing-key/"+vaultBmcKeyName, nil)
 	// Handle any general error with the request.
 	if err != nil {
 		log.Printf(
 			"Unable to get the %s secret from vault: %s  Error was: %s",
-			vaultBmcKeyName, vaultBase, err)
+			vaultBmcKeyName, vaultSession.baseURL, err)
 		return "", response, responseCode, fmt.Errorf("Unable to get the %s secret from vault: %s  Error was: %s",
-			vaultBmcKeyName, vaultBase, err)
+			vaultBmcKeyName, vaultSession.baseURL, err)
 	}
 
 	if responseCode == http.StatusNotFound {
@@ -176,7 +131,10 @@ func vaultExportPrivateKey(vaultToken string) (pvtKey string, response []byte, r
 	}
 }
 
-var previousPrivateKeyHash []byte = nil
+// Fingerprint of the installed key, and hash of the last key rejected
+var mountainKeyMutex = &sync.Mutex{}
+var installedKeyFingerprint string = ""
+var rejectedKeyHash []byte = nil
 
 // function to hash a string
 func hashString(s string) ([]byte, error) {
@@ -187,99 +145,172 @@ func hashString(s string) ([]byte, error) {
 	return hasher.Sum(nil), nil
 }
 
-// Ensure that Mountain node console key files are present.
-func ensureMountainConsoleKeysPresent() bool {
-	// NOTE: in update config thread
-	retVal := false
+// Fingerprint of the key pair already on disk, empty if there is no valid one
+// NOTE: this lets a restarted pod see that the key has not changed
+func diskKeyFingerprint() string {
+	data, err := os.ReadFile(mountainConsoleKey)
+	if err != nil {
+		return ""
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		log.Printf("Installed Mountain ssh key is not valid: %s", err)
+		return ""
+	}
+	if _, err := os.Stat(mountainConsoleKeyPub); err != nil {
+		return ""
+	}
+	return ssh.FingerprintSHA256(signer.PublicKey())
+}
 
-	// if running in debug mode there won't be any nodes or vault present
-	if debugOnly {
-		log.Print("Running in debug mode - skipping mountain cred generation")
-		return retVal
+// Write a file next to its final location then move it into place
+func writeFileAtomic(fn string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fn), "."+filepath.Base(fn)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
 	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fn)
+}
 
-	// if there are no mountain nodes configured do not download the ssh key
-	if len(currentMtnNodes) == 0 {
-		log.Printf("No mountain nodes configured, not checking for keys")
-		return retVal
+// Keep a copy of the current file as fn.prev so a bad key can be rolled back
+func keepPreviousFile(fn string) error {
+	data, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
 	}
+	return writeFileAtomic(fn+".prev", data, 0600)
+}
 
-	// Authenticate to Vault
-	svcAcctToken, err := os.ReadFile(svcAcctTokenFile)
+// Validate the private key and install it with its public key
+func installMountainConsoleKey(pvtKey string) (string, error) {
+	// make sure ssh can use the key before replacing the working one
+	signer, err := ssh.ParsePrivateKey([]byte(pvtKey))
 	if err != nil {
-		log.Printf("Unable to read the service account token file: %s  Can not authenticate to vault.", err)
-		return retVal
+		return "", fmt.Errorf("invalid private ssh key received from Vault: %s", err)
 	}
+	pubKey := ssh.MarshalAuthorizedKey(signer.PublicKey())
 
-	// get the vault base URL
-	vaultBase := os.Getenv("VAULT_URL")
-	if len(vaultBase) == 0 {
-		log.Printf("Error: VAULT_URL environment variable is not set, defaulting to http://cray-vault.vault:8200/v1")
-		vaultBase = "http://cray-vault.vault:8200/v1"
+	// keep the old pair, then replace each file in a single step
+	if err := keepPreviousFile(mountainConsoleKey); err != nil {
+		return "", fmt.Errorf("unable to keep the previous ssh key: %s", err)
 	}
+	if err := keepPreviousFile(mountainConsoleKeyPub); err != nil {
+		return "", fmt.Errorf("unable to keep the previous ssh public key: %s", err)
+	}
+	if err := writeFileAtomic(mountainConsoleKey, []byte(pvtKey), 0600); err != nil {
+		return "", fmt.Errorf("failed to write out the private ssh key received from Vault: %s", err)
+	}
+	if err := writeFileAtomic(mountainConsoleKeyPub, pubKey, 0644); err != nil {
+		// put the old private key back so the pair on disk still matches
+		if prev, perr := os.ReadFile(mountainConsoleKey + ".prev"); perr == nil {
+			writeFileAtomic(mountainConsoleKey, prev, 0600)
+		}
+		return "", fmt.Errorf("failed to write out the public ssh key: %s", err)
+	}
+	return ssh.FingerprintSHA256(signer.PublicKey()), nil
+}
 
-	// Authenticate to Vault using the service account token
+// Check if there are any Mountain consoles that need the ssh key
+func haveMountainConsoles() bool {
+	currNodesMutex.Lock()
+	defer currNodesMutex.Unlock()
+	return len(currentMtnNodes) > 0
+}
 
-	// The ssh key is created and deployed by console-operator, this just waits until
-	// it is available in vault and downloads it to the local file system for use.
-	vaultToken := ""
-	for {
-		// Authenticate to Vault using the service account token
-		vaultAuthParam := map[string]string{
-			"jwt":  string(svcAcctToken),
-			"role": "ssh-user-certs-compute"}
-		jsonVaultAuthParam, err := json.Marshal(vaultAuthParam)
-		if err != nil {
-			log.Printf("Error: Failed to marshal the vault authentication parameters. Err: %s", err)
-		} else {
-			URL := vaultBase + "/auth/kubernetes/login"
-			log.Printf("Attempting to authenticate to Vault at: %s", URL)
-			response, responseCode, _ := postURL(URL, jsonVaultAuthParam, nil)
+// Ensure that Mountain node console key files are present, returns if the key changed.
+// NOTE: this makes a single attempt and does not need the node lock
+func ensureMountainConsoleKeysPresent() (bool, error) {
+	// if running in debug mode there won't be any nodes or vault present
+	if debugOnly {
+		log.Print("Running in debug mode - skipping mountain cred generation")
+		return false, nil
+	}
 
-			// If the response code is not 200 then we failed authentication.
-			if responseCode == http.StatusOK {
-				log.Printf("Vault authentication was successful.  Attempting to get BMC console key from vault")
-				vaultToken = gjson.Get(string(response), "auth.client_token").String()
-				break
-			}
-		}
+	// certificates are rotated in place, conman never needs restarting for them
+	if mountainAuthMode == "cert" {
+		return false, ensureMountainCert()
+	}
 
-		// Can't access vault, wait and try again
-		log.Printf("Unable to authenticate with vault, will try again in 15 seconds")
-		time.Sleep(15 * time.Second)
+	// The ssh key is created and deployed by console-operator, this just checks
+	// if it is available in vault and downloads it to the local file system for use.
+	pvtKey, _, responseCode, err := vaultExportPrivateKey()
+	if err != nil {
+		return false, err
+	}
+	if responseCode != http.StatusOK {
+		return false, fmt.Errorf("mountain ssh keys do not exist yet")
 	}
 
-	// Authentication was successful, now retrieve the private key.
-	for {
-		// Ask vault for the existing key
-		pvtKey, _, responseCode, _ := vaultExportPrivateKey(vaultToken)
-		if responseCode == http.StatusOK {
-			// see if this is the same key as before
-			newHash, err := hashString(pvtKey)
-			if err != nil {
-				log.Printf("Error: Failed to hash the private ssh key received from Vault. Err: %s", err)
-			} else if previousPrivateKeyHash == nil || !(bytes.Equal(newHash, previousPrivateKeyHash)) {
-				// This is a new key
-				retVal = true
-				previousPrivateKeyHash = newHash
+	mountainKeyMutex.Lock()
+	defer mountainKeyMutex.Unlock()
+
+	// see if this is the same key as before
+	newHash, err := hashString(pvtKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash the private ssh key received from Vault: %s", err)
+	}
+	if installedKeyFingerprint == "" {
+		installedKeyFingerprint = diskKeyFingerprint()
+	}
+	if signer, err := ssh.ParsePrivateKey([]byte(pvtKey)); err == nil &&
+		ssh.FingerprintSHA256(signer.PublicKey()) == installedKeyFingerprint {
+		// This is the same key as before, no need to write it again
+		log.Printf("Mountain ssh key file already exists")
+		return false, nil
+	}
 
-				// Write the private key to the local file system.
-				err = os.WriteFile(mountainConsoleKey, []byte(pvtKey), 0600)
-				if err != nil {
-					log.Printf("Error: Failed to write our the private ssh key received from Vault. Err: %s", err)
-					continue
-				}
-				log.Printf("Mountain ssh key file created")
-				return retVal
-			} else {
-				// This is the same key as before, no need to write it again
-				log.Printf("Mountain ssh key file already exists")
-				return retVal
-			}
+	// install the new key, leaving the old one in place if it is not usable
+	fingerprint, err := installMountainConsoleKey(pvtKey)
+	if err != nil {
+		if !bytes.Equal(newHash, rejectedKeyHash) {
+			raiseEvent("", "mountain_key_rejected", eventWarning,
+				fmt.Sprintf("new Mountain console key not installed: %s", err),
+				map[string]string{"installed_fingerprint": installedKeyFingerprint})
+			rejectedKeyHash = newHash
 		}
+		return false, err
+	}
+	rejectedKeyHash = nil
+	log.Printf("Mountain ssh key file created, fingerprint %s", fingerprint)
+	raiseEvent("", "mountain_key_changed", eventInfo,
+		fmt.Sprintf("Mountain console key changed to %s", fingerprint),
+		map[string]string{"fingerprint": fingerprint, "previous_fingerprint": installedKeyFingerprint})
+	installedKeyFingerprint = fingerprint
+	return true, nil
+}
 
-		// keys do not exist, wait and try again
-		log.Printf("Mountain ssh keys do not exist - waiting for them...")
-		time.Sleep(5 * time.Second)
+// Wait until the Mountain console key is present before configuring conman
+// NOTE: in update config thread, without the node lock so heartbeats and
+// acquisition carry on while vault is unavailable
+func waitForMountainConsoleKeys() {
+	for haveMountainConsoles() {
+		_, err := ensureMountainConsoleKeysPresent()
+		if err == nil {
+			return
+		}
+		wait := vaultSession.retryDelay()
+		if wait < 15*time.Second {
+			wait = 15 * time.Second
+		}
+		log.Printf("Mountain ssh key not available, trying again in %s: %s", wait.Round(time.Second), err)
+		time.Sleep(wait)
 	}
 }
@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2021-2025 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2021-2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -34,9 +34,6 @@ import (
 	"time"
 )
 
-// Variable to hold address of console-data service
-var dataAddrBase string = "http://cray-console-data/v1"
-
 // Time to wait for sending the heartbeat to console-data
 var heartbeatIntervalSecs int = 30
 
@@ -67,11 +64,31 @@ type NodePodInfo struct {
 	NumActivePods int `json:"numactivepods"`
 }
 
+// DataService - interface for interacting with the console-data service
+type DataService interface {
+	getNumActiveNodePods() (int, error)
+	acquireNewNodes(numMtn, numRvr int, podLocation *PodLocationDataResponse) []nodeConsoleInfo
+	sendHeartbeat(heartBeatPayload nodeConsoleInfoHeartBeat) ([]nodeConsoleInfo, error)
+	releaseNodes(nodes []nodeConsoleInfo) error
+}
+
+// DataManager - struct for managing console-data interactions
+type DataManager struct {
+	dataAddrBase string
+}
+
+// NewDataService creates a new instance of DataManager
+func NewDataService() *DataManager {
+	return &DataManager{
+		dataAddrBase: "http://cray-console-data/v1",
+	}
+}
+
 // Query the console-data pod to get the number of currently active console-node pods
-func getNumActiveNodePods() (int, error) {
+func (dm DataManager) getNumActiveNodePods() (int, error) {
 	retVal := 1
 	// make the call to console-data
-	url := fmt.Sprintf("%s/activepods", dataAddrBase)
+	url := fmt.Sprintf("%s/activepods", dm.dataAddrBase)
 	rb, _, err := getURL(url, nil)
 	if err != nil {
 		log.Printf("Error in console-data active pods query: %s", err)
@@ -93,7 +110,7 @@ func getNumActiveNodePods() (int, error) {
 }
 
 // Function to acquire new consoles to monitor
-func acquireNewNodes(numMtn, numRvr int, podLocation *PodLocationDataResponse) []nodeConsoleInfo {
+func (dm DataManager) acquireNewNodes(numMtn, numRvr int, podLocation *PodLocationDataResponse) []nodeConsoleInfo {
 	// NOTE: in doGetNewNodes thread
 	log.Printf("Acquiring new nodes mtn: %d, rvr: %d", numMtn, numRvr)
 	// put together data package
@@ -114,7 +131,7 @@ func acquireNewNodes(numMtn, numRvr int, podLocation *PodLocationDataResponse) [
 		return nil
 	}
 	// make the call to console-data
-	url := fmt.Sprintf("%s/consolepod/%s/acquire", dataAddrBase, podID)
+	url := fmt.Sprintf("%s/consolepod/%s/acquire", dm.dataAddrBase, podID)
 	rb, _, err := postURL(url, data, nil)
 	if err != nil {
 		log.Printf("Error in console-data acquire: %s", err)
@@ -137,9 +154,6 @@ func sendSingleHeartbeat() {
 	currNodesMutex.Lock()
 	defer currNodesMutex.Unlock()
 
-	// create the url for the heartbeat of this pod
-	url := fmt.Sprintf("%s/consolepod/%s/heartbeat", dataAddrBase, podID)
-
 	// gather the current nodes and assemble into json data
 	currNodes := make([]NodeConsoleInfo, 0, len(currentMtnNodes)+len(currentRvrNodes)+len(currentPdsNodes))
 	heartBeatPayload := nodeConsoleInfoHeartBeat{CurrNodes: currNodes, PodLocation: podLocData.Xname}
@@ -161,43 +175,57 @@ func sendSingleHeartbeat() {
 		}
 	}
 
-	//log.Printf("heartBeatPayload: %+v\n", heartBeatPayload)
-	data, err := json.Marshal(heartBeatPayload)
-	if err != nil {
-		log.Printf("Error marshalling data for add nodes:%s", err)
-		return
-	}
-
 	// log last heartbeat time
 	t := time.Now()
 	lastHeartbeatTime = t.Format(time.RFC3339)
 
-	// make the http call
+	// send the heartbeat
 	log.Printf("Pod: %s sending heartbeat", podID)
-	rb, _, err := postURL(url, data, nil)
+	droppedNodes, err := dataService.sendHeartbeat(heartBeatPayload)
 	if err != nil {
 		log.Printf("Error sending heartbeat: %s", err)
+		return
 	}
 
 	// process the nodes no longer controlled by this pod
-	if rb != nil {
-		// should be an array of nodeConsoleInfo structs
-		var droppedNodes []nodeConsoleInfo
-		err := json.Unmarshal(rb, &droppedNodes)
-		if err != nil {
-			log.Printf("Error unmarshalling heartbeat return data: %s", err)
-		} else if len(droppedNodes) > 0 {
-			log.Printf("Heartbeat: There are %d dropped nodes", len(droppedNodes))
+	if len(droppedNodes) > 0 {
+		log.Printf("Heartbeat: There are %d dropped nodes", len(droppedNodes))
 
-			// release the nodes
-			for _, ni := range droppedNodes {
-				releaseNode(ni.NodeName)
-			}
+		// release the nodes
+		for _, ni := range droppedNodes {
+			releaseNode(ni.NodeName)
+		}
+
+		// signal conman to restart/reconfigure
+		signalConmanTERM()
+	}
+}
 
-			// signal conman to restart/reconfigure
-			signalConmanTERM()
+// Send the heartbeat to console-data, returns the nodes no longer controlled by this pod
+func (dm DataManager) sendHeartbeat(heartBeatPayload nodeConsoleInfoHeartBeat) ([]nodeConsoleInfo, error) {
+	// create the url for the heartbeat of this pod
+	url := fmt.Sprintf("%s/consolepod/%s/heartbeat", dm.dataAddrBase, podID)
+
+	//log.Printf("heartBeatPayload: %+v\n", heartBeatPayload)
+	data, err := json.Marshal(heartBeatPayload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling heartbeat data: %s", err)
+	}
+
+	// make the http call
+	rb, _, err := postURL(url, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// should be an array of nodeConsoleInfo structs
+	var droppedNodes []nodeConsoleInfo
+	if rb != nil {
+		if err := json.Unmarshal(rb, &droppedNodes); err != nil {
+			return nil, fmt.Errorf("error unmarshalling heartbeat return data: %s", err)
 		}
 	}
+	return droppedNodes, nil
 }
 
 // Function to send heartbeat to console-data
@@ -221,22 +249,31 @@ func releaseNodes(nodes []nodeConsoleInfo) {
 	// NOTE: in doGetNewNodes thread
 	// NOTE: also called from releaseAllNodes when shutting down
 
-	// create the url for the heartbeat of this pod
-	url := fmt.Sprintf("%s/consolepod/%s/release", dataAddrBase, podID)
+	// nothing to give the nodes back to when running standalone
+	if standaloneMode() {
+		return
+	}
+
+	log.Printf("Pod: %s releasing nodes", podID)
+	if err := dataService.releaseNodes(nodes); err != nil {
+		log.Printf("Error releasing nodes: %s", err)
+	}
+}
+
+// Tell console-data the nodes are no longer monitored by this pod
+func (dm DataManager) releaseNodes(nodes []nodeConsoleInfo) error {
+	// create the url for the release of this pod
+	url := fmt.Sprintf("%s/consolepod/%s/release", dm.dataAddrBase, podID)
 
 	// gather the current nodes and assemble into json data
 	data, err := json.Marshal(nodes)
 	if err != nil {
-		log.Printf("Error marshalling data for add nodes:%s", err)
-		return
+		return fmt.Errorf("error marshalling data for release nodes: %s", err)
 	}
 
 	// make the http call
-	log.Printf("Pod: %s releasing nodes", podID)
 	_, _, err = postURL(url, data, nil)
-	if err != nil {
-		log.Printf("Error releasing nodes: %s", err)
-	}
+	return err
 }
 
 //========================================
@@ -292,7 +329,7 @@ func createTestLogFile(xname string, respin bool) {
 	//  are no real console connections present.
 
 	var sleepTime time.Duration = 1 * time.Second
-	filename := fmt.Sprintf("/var/log/conman/console.%s", xname)
+	filename := consoleLogFile(xname)
 
 	// Ff respin is true, only create if the file is not present - meant to
 	// be used when a logrotation has moved the original file and we need to
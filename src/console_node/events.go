@@ -0,0 +1,136 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the events raised about the consoles monitored by this pod
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Severities of events
+const (
+	eventInfo     string = "info"
+	eventWarning  string = "warning"
+	eventCritical string = "critical"
+)
+
+// Number of events kept in memory
+var eventMaxRecords int = 1000
+
+// ConsoleEvent - something noteworthy that happened on a console
+type ConsoleEvent struct {
+	ID       int64             `json:"id"`
+	Time     time.Time         `json:"time"`
+	Xname    string            `json:"xname"`
+	Type     string            `json:"type"`
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+// Globals holding the recent events
+var eventsMutex = &sync.Mutex{}
+var recentEvents []ConsoleEvent = nil
+var lastEventID int64 = 0
+
+// Read the event configuration from the environment
+func initEvents() {
+	readSingleEnvVarInt("EVENT_MAX_RECORDS", &eventMaxRecords, 10, 100000)
+}
+
+// Raise an event about a console
+func raiseEvent(xname, etype, severity, message string, details map[string]string) {
+	log.Printf("Event %s (%s) on %s: %s", etype, severity, xname, message)
+
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+	lastEventID++
+	recentEvents = append(recentEvents, ConsoleEvent{
+		ID:       lastEventID,
+		Time:     time.Now(),
+		Xname:    xname,
+		Type:     etype,
+		Severity: severity,
+		Message:  message,
+		Details:  details,
+	})
+	if len(recentEvents) > eventMaxRecords {
+		recentEvents = recentEvents[len(recentEvents)-eventMaxRecords:]
+	}
+}
+
+// Gather the events after the given id that match the filters
+func getEvents(afterID int64, xname, etype string) []ConsoleEvent {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+	evs := []ConsoleEvent{}
+	for _, ev := range recentEvents {
+		if ev.ID <= afterID {
+			continue
+		}
+		if (xname != "" && ev.Xname != xname) || (etype != "" && ev.Type != etype) {
+			continue
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// Handle a request for the recent events
+func doEvents(w http.ResponseWriter, r *http.Request) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	// callers poll with the id of the last event they saw
+	q := r.URL.Query()
+	var afterID int64 = 0
+	if v := q.Get("after"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid after: %s", v))
+			return
+		}
+		afterID = id
+	}
+	evs := getEvents(afterID, q.Get("xname"), q.Get("type"))
+	allowed := evs[:0]
+	for _, ev := range evs {
+		if authAllowsXname(r, ev.Xname) {
+			allowed = append(allowed, ev)
+		}
+	}
+	SendResponseJSON(w, http.StatusOK, allowed)
+}
@@ -0,0 +1,253 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the protection of the aggregation log and the console
+// streams from consoles flooding output
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Budgets for the output of a single console
+// NOTE: a value of zero turns off that budget
+var floodLinesPerSec int = 500    // lines per second passed on from a console
+var floodBytesPerSec int = 262144 // bytes per second passed on from a console
+var floodWindowSecs int = 10      // window the budgets are measured over
+
+// FloodStatus - the suppression of output from a single console
+type FloodStatus struct {
+	Flooding        bool      `json:"flooding"`
+	FloodingSince   time.Time `json:"flooding_since,omitempty"`
+	SuppressedLines int64     `json:"suppressed_lines"`
+	SuppressedBytes int64     `json:"suppressed_bytes"`
+}
+
+// The budget tracking of a single console
+type floodLimiter struct {
+	FloodStatus
+	windowStart time.Time
+	lines       int
+	bytes       int
+	suppLines   int64 // lines suppressed in the current window
+	suppBytes   int64 // bytes suppressed in the current window
+}
+
+// Globals holding the budget tracking of each console
+var floodMutex = &sync.Mutex{}
+var floodLimiters map[string]*floodLimiter = make(map[string]*floodLimiter) // [xname,*floodLimiter]
+
+// Read the flood budgets from the environment
+func initFloodProtection() {
+	readSingleEnvVarInt("FLOOD_LINES_PER_SEC", &floodLinesPerSec, 0, 1000000)
+	readSingleEnvVarInt("FLOOD_BYTES_PER_SEC", &floodBytesPerSec, 0, 1<<30)
+	readSingleEnvVarInt("FLOOD_WINDOW_SEC", &floodWindowSecs, 1, 3600)
+
+	go watchFloodWindows()
+}
+
+// Start tracking the budgets of a console
+func startFloodLimiter(xname string) {
+	floodMutex.Lock()
+	defer floodMutex.Unlock()
+	if _, ok := floodLimiters[xname]; !ok {
+		floodLimiters[xname] = &floodLimiter{}
+	}
+}
+
+// Stop tracking the budgets of a console that is no longer monitored
+func removeFloodLimiter(xname string) {
+	floodMutex.Lock()
+	defer floodMutex.Unlock()
+	delete(floodLimiters, xname)
+}
+
+// Close out the current window, returning a summary of what was suppressed
+// and if the console is no longer flooding
+func (fl *floodLimiter) endWindow(when time.Time) (string, bool) {
+	summary := ""
+	ended := false
+	if fl.suppLines > 0 {
+		summary = fmt.Sprintf("[console-node] suppressed %d lines (%d bytes) in %ds",
+			fl.suppLines, fl.suppBytes, int(when.Sub(fl.windowStart).Seconds()+0.5))
+	} else if fl.Flooding {
+		fl.Flooding = false
+		ended = true
+	}
+	fl.windowStart = when
+	fl.lines, fl.bytes = 0, 0
+	fl.suppLines, fl.suppBytes = 0, 0
+	return summary, ended
+}
+
+// Check a line of output against the budgets of the console
+func floodCheck(xname, text string, when time.Time) (allow bool, summary string) {
+	// NOTE: the events are raised once the lock is released
+	started, ended := false, false
+	defer func() {
+		if ended {
+			raiseFloodEnded(xname)
+		}
+		if started {
+			raiseFloodStarted(xname)
+		}
+	}()
+
+	floodMutex.Lock()
+	defer floodMutex.Unlock()
+	fl, ok := floodLimiters[xname]
+	if !ok {
+		return true, ""
+	}
+
+	window := time.Duration(floodWindowSecs) * time.Second
+	if when.Sub(fl.windowStart) >= window {
+		summary, ended = fl.endWindow(when)
+	}
+
+	fl.lines++
+	fl.bytes += len(text) + 1
+	if (floodLinesPerSec > 0 && fl.lines > floodLinesPerSec*floodWindowSecs) ||
+		(floodBytesPerSec > 0 && fl.bytes > floodBytesPerSec*floodWindowSecs) {
+		fl.suppLines++
+		fl.suppBytes += int64(len(text)) + 1
+		fl.SuppressedLines++
+		fl.SuppressedBytes += int64(len(text)) + 1
+		if !fl.Flooding {
+			fl.Flooding = true
+			fl.FloodingSince = when
+			started = true
+		}
+		return false, summary
+	}
+	return true, summary
+}
+
+// Let the event listeners know a console went over its budget
+func raiseFloodStarted(xname string) {
+	raiseEvent(xname, "console_flood", eventWarning,
+		fmt.Sprintf("console %s is over its output budget, output is being suppressed", xname), nil)
+}
+
+// Let the event listeners know a console is back within its budget
+func raiseFloodEnded(xname string) {
+	raiseEvent(xname, "console_flood_ended", eventInfo,
+		fmt.Sprintf("console %s is back within its output budget", xname), nil)
+}
+
+// Close out the windows of consoles that have gone quiet while suppressed
+func watchFloodWindows() {
+	for {
+		time.Sleep(time.Duration(floodWindowSecs) * time.Second)
+
+		type pending struct{ xname, summary string }
+		var summaries []pending
+		var ended []string
+		now := time.Now()
+		window := time.Duration(floodWindowSecs) * time.Second
+		floodMutex.Lock()
+		for xname, fl := range floodLimiters {
+			if (fl.suppLines > 0 || fl.Flooding) && now.Sub(fl.windowStart) >= window {
+				s, e := fl.endWindow(now)
+				if s != "" {
+					summaries = append(summaries, pending{xname, s})
+				}
+				if e {
+					ended = append(ended, xname)
+				}
+			}
+		}
+		floodMutex.Unlock()
+
+		for _, p := range summaries {
+			writeFloodSummary(p.xname, p.summary, now)
+		}
+		for _, xname := range ended {
+			raiseFloodEnded(xname)
+		}
+	}
+}
+
+// Pass the summary of suppressed output on in place of the output itself
+func writeFloodSummary(xname, summary string, when time.Time) {
+	writeConsoleToAggLog(xname, summary, when)
+	recordConsoleLine(xname, summary, when)
+}
+
+// Get the flood status of a console
+func getFloodStatus(xname string) (FloodStatus, bool) {
+	floodMutex.Lock()
+	defer floodMutex.Unlock()
+	if fl, ok := floodLimiters[xname]; ok {
+		return fl.FloodStatus, true
+	}
+	return FloodStatus{}, false
+}
+
+// Names of the consoles currently over their budgets
+func floodingConsoles() []string {
+	floodMutex.Lock()
+	defer floodMutex.Unlock()
+	var xnames []string
+	for xname, fl := range floodLimiters {
+		if fl.Flooding {
+			xnames = append(xnames, xname)
+		}
+	}
+	sort.Strings(xnames)
+	return xnames
+}
+
+// Write out the flood protection metrics
+func writeFloodMetrics(mw *metricsWriter) {
+	floodMutex.Lock()
+	defer floodMutex.Unlock()
+
+	xnames := make([]string, 0, len(floodLimiters))
+	for xname := range floodLimiters {
+		xnames = append(xnames, xname)
+	}
+	sort.Strings(xnames)
+
+	mw.header("console_node_console_flooding", "gauge", "Whether the console is over its output budget")
+	for _, xname := range xnames {
+		flooding := 0.0
+		if floodLimiters[xname].Flooding {
+			flooding = 1
+		}
+		mw.value("console_node_console_flooding", flooding, "xname", xname)
+	}
+	mw.header("console_node_console_suppressed_lines_total", "counter", "Lines of console output suppressed by the output budget")
+	for _, xname := range xnames {
+		mw.value("console_node_console_suppressed_lines_total", float64(floodLimiters[xname].SuppressedLines), "xname", xname)
+	}
+	mw.header("console_node_console_suppressed_bytes_total", "counter", "Bytes of console output suppressed by the output budget")
+	for _, xname := range xnames {
+		mw.value("console_node_console_suppressed_bytes_total", float64(floodLimiters[xname].SuppressedBytes), "xname", xname)
+	}
+}
@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2021-2022, 2024 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2021-2022, 2024, 2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -34,11 +34,20 @@ import (
 
 // HealthResponse - used to report service health stats
 type HealthResponse struct {
-	NumMtnConnected string `json:"num_mtn"`
-	NumRvrConnected string `json:"num_rvr"`
-	TargetNumMtn    string `json:"target_mtn"`
-	TargetNumRvr    string `json:"target_rvr"`
-	LastHeartbeat   string `json:"last_heartbeat"`
+	NumMtnConnected  string              `json:"num_mtn"`
+	NumRvrConnected  string              `json:"num_rvr"`
+	TargetNumMtn     string              `json:"target_mtn"`
+	TargetNumRvr     string              `json:"target_rvr"`
+	LastHeartbeat    string              `json:"last_heartbeat"`
+	Standalone       bool                `json:"standalone,omitempty"`
+	Simulated        bool                `json:"simulated,omitempty"`
+	FloodingConsoles []string            `json:"flooding_consoles,omitempty"`
+	PendingCreds     []string            `json:"pending_credentials,omitempty"`
+	PendingTenants   []string            `json:"pending_tenants,omitempty"`
+	VaultAuth        VaultAuthStatus     `json:"vault_auth"`
+	CredsVaultAuth   *VaultAuthStatus    `json:"creds_vault_auth,omitempty"`
+	MountainCert     *MountainCertStatus `json:"mountain_cert,omitempty"`
+	AggWriter        AggWriterStats      `json:"agg_writer"`
 }
 
 // ErrResponse - Simple struct to return error information
@@ -74,6 +83,15 @@ func doHealth(w http.ResponseWriter, r *http.Request) {
 	stats.TargetNumMtn = fmt.Sprintf("%d", targetMtnNodes)
 	stats.TargetNumRvr = fmt.Sprintf("%d", targetRvrNodes)
 	stats.LastHeartbeat = lastHeartbeatTime
+	stats.Standalone = standaloneMode()
+	stats.Simulated = simulationMode()
+	stats.FloodingConsoles = floodingConsoles()
+	stats.PendingCreds = pendingCredentialConsoles()
+	stats.PendingTenants = pendingTenantConsoles()
+	stats.VaultAuth = vaultSession.status()
+	stats.CredsVaultAuth = credsVaultStatus()
+	stats.MountainCert = getMountainCertStatus()
+	stats.AggWriter = getAggWriterStats()
 
 	// write the output
 	w.WriteHeader(http.StatusOK)
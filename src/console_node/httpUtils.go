@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2019-2022, 2024-2025 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2019-2022, 2024-2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -32,6 +32,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Send error or empty OK response
@@ -145,3 +147,15 @@ func getURL(URL string, requestHeaders map[string]string) ([]byte, int, error) {
 	//fmt.Printf("Data: %s\n", data)
 	return data, resp.StatusCode, err
 }
+
+// Parse a time query parameter given as RFC3339 or unix seconds
+func parseTimeParam(val string) (time.Time, error) {
+	// an empty value means no time was given
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
@@ -0,0 +1,469 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the extraction of hardware errors reported on the
+// node consoles into structured records
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of hardware errors that are recognized
+const (
+	hwErrMCE  string = "mce"
+	hwErrEDAC string = "edac"
+	hwErrAER  string = "aer"
+	hwErrNVMe string = "nvme"
+	hwErrNIC  string = "nic"
+)
+
+// Severities of hardware errors
+const (
+	hwSevCorrected   string = "corrected"
+	hwSevUncorrected string = "uncorrected"
+	hwSevFatal       string = "fatal"
+	hwSevError       string = "error"
+)
+
+// Number of records kept for each node
+var hwErrorMaxRecords int = 500
+
+// Default window used when rolling up the error counts
+var hwErrorDefaultWindow time.Duration = 24 * time.Hour
+
+// HwErrorRecord - a single hardware error seen on a console
+type HwErrorRecord struct {
+	Xname     string            `json:"xname"`
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"type"`
+	Component string            `json:"component"`
+	Severity  string            `json:"severity"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Line      string            `json:"line"`
+}
+
+// HwErrorNodeSummary - rolled up error counts for a single node
+type HwErrorNodeSummary struct {
+	Total      int            `json:"total"`
+	ByType     map[string]int `json:"by_type"`
+	BySeverity map[string]int `json:"by_severity"`
+	LastError  *HwErrorRecord `json:"last_error,omitempty"`
+	Lifetime   map[string]int `json:"lifetime"`
+}
+
+// HwErrorResponse - rolled up error counts across the monitored nodes
+type HwErrorResponse struct {
+	Since  time.Time                      `json:"since"`
+	Window string                         `json:"window"`
+	Totals map[string]int                 `json:"totals"`
+	Nodes  map[string]*HwErrorNodeSummary `json:"nodes"`
+}
+
+// The errors and counters kept for a single node
+type nodeHwErrors struct {
+	records  []HwErrorRecord // ring of the most recent records
+	next     int             // next slot in the ring to fill
+	lifetime map[string]int  // [type,count] since the console was first monitored
+}
+
+// Globals holding the errors for all nodes
+var hwErrorsMutex = &sync.Mutex{}
+var nodeErrors map[string]*nodeHwErrors = make(map[string]*nodeHwErrors) // [xname,*nodeHwErrors]
+
+// A parser that recognizes a single form of hardware error message
+type hwErrorParser struct {
+	errType string
+	re      *regexp.Regexp
+	build   func(fields map[string]string) (component, severity string)
+	detail  bool // adds fields to the previous record instead of a new one
+}
+
+// The PCI address of a device in the form 0000:00:00.0
+const bdfPattern string = `[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-9a-fA-F]{2}\.[0-7]`
+
+// All of the hardware error messages that are recognized
+// NOTE: the parsed fields come from the named groups of each expression
+var hwErrorParsers = []hwErrorParser{
+	{
+		// mce: [Hardware Error]: CPU 3: Machine Check: 0 Bank 5: be00000000800400
+		errType: hwErrMCE,
+		re:      regexp.MustCompile(`\[Hardware Error\]: CPU (?P<cpu>\d+): Machine Check(?: Exception)?: (?P<mcgstatus>[0-9a-fA-F]+) Bank (?P<bank>\d+): (?P<status>[0-9a-fA-F]+)`),
+		build: func(f map[string]string) (string, string) {
+			return "cpu" + f["cpu"], mceSeverity(f["status"])
+		},
+	},
+	{
+		// mce: [Hardware Error]: TSC 0 ADDR 1ffff8a5c0 MISC 9001e6000c0086
+		errType: hwErrMCE,
+		re:      regexp.MustCompile(`\[Hardware Error\]: .*\bADDR (?P<address>[0-9a-fA-Fx]+)`),
+		build: func(f map[string]string) (string, string) {
+			return "", ""
+		},
+		detail: true,
+	},
+	{
+		// EDAC MC0: 1 CE memory read error on CPU_SrcID#0_MC#0_Chan#1_DIMM#0 (channel:1 slot:0 page:0x12a3 offset:0x0 ...)
+		errType: hwErrEDAC,
+		re:      regexp.MustCompile(`EDAC (?:\S+ )?(?P<mc>MC\d+): (?P<count>\d+) (?P<kind>CE|UE) (?P<desc>.*?) on (?P<dimm>\S+)(?: \((?P<detail>[^)]*)\))?`),
+		build: func(f map[string]string) (string, string) {
+			for _, kv := range strings.Fields(f["detail"]) {
+				if k, v, ok := strings.Cut(kv, ":"); ok && v != "" {
+					switch k {
+					case "channel", "slot", "page", "offset", "grain", "syndrome":
+						f[k] = v
+					}
+				}
+			}
+			delete(f, "detail")
+			if f["kind"] == "UE" {
+				return f["dimm"], hwSevUncorrected
+			}
+			return f["dimm"], hwSevCorrected
+		},
+	},
+	{
+		// pcieport 0000:00:03.1: PCIe Bus Error: severity=Corrected, type=Physical Layer, (Receiver ID)
+		errType: hwErrAER,
+		re:      regexp.MustCompile(`(?P<bdf>` + bdfPattern + `): PCIe Bus Error: severity=(?P<aer_severity>[^,]+), type=(?P<aer_type>[^,]+)`),
+		build: func(f map[string]string) (string, string) {
+			sev := strings.ToLower(f["aer_severity"])
+			switch {
+			case strings.Contains(sev, "fatal") && !strings.Contains(sev, "non-fatal"):
+				return f["bdf"], hwSevFatal
+			case strings.HasPrefix(sev, "uncorrected"):
+				return f["bdf"], hwSevUncorrected
+			}
+			return f["bdf"], hwSevCorrected
+		},
+	},
+	{
+		// nvme0n1: I/O Cmd(0x2) @ LBA 5912, 8 blocks, I/O Error (sct 0x2 / sc 0x81)
+		errType: hwErrNVMe,
+		re:      regexp.MustCompile(`(?P<device>nvme\d+n\d+): I/O Cmd\((?P<opcode>0x[0-9a-fA-F]+)\) @ LBA (?P<lba>\d+), (?P<blocks>\d+) blocks, I/O Error \(sct (?P<sct>0x[0-9a-fA-F]+) / sc (?P<sc>0x[0-9a-fA-F]+)\)`),
+		build: func(f map[string]string) (string, string) {
+			return f["device"], hwSevError
+		},
+	},
+	{
+		// critical medium error, dev nvme0n1, sector 5912 op 0x0:(READ)
+		errType: hwErrNVMe,
+		re:      regexp.MustCompile(`critical (?P<kind>medium|target|nexus|space allocation) error, dev (?P<device>nvme\d+n\d+), sector (?P<sector>\d+)`),
+		build: func(f map[string]string) (string, string) {
+			return f["device"], hwSevUncorrected
+		},
+	},
+	{
+		// nvme nvme0: I/O 123 QID 4 timeout, aborting
+		// nvme nvme0: controller is down; will reset: CSTS=0xffffffff
+		errType: hwErrNVMe,
+		re:      regexp.MustCompile(`nvme (?P<device>nvme\d+): (?P<message>.*(?:timeout|controller is down|Device not ready|Removing after probe failure|failed).*)`),
+		build: func(f map[string]string) (string, string) {
+			if strings.Contains(f["message"], "controller is down") || strings.Contains(f["message"], "Removing") {
+				return f["device"], hwSevFatal
+			}
+			return f["device"], hwSevError
+		},
+	},
+	{
+		// cxi_core 0000:21:00.0: cxi0[hsn0]: C_EC_CRIT: ...
+		// cxi_core 0000:21:00.0: cxi0[hsn0]: link down
+		errType: hwErrNIC,
+		re:      regexp.MustCompile(`cxi_\w+ (?P<bdf>` + bdfPattern + `): (?P<device>cxi\d+)(?:\[(?P<interface>\w+)\])?: (?P<message>.*(?:CRIT|ERR|[Ee]rror|link down|fail).*)`),
+		build: func(f map[string]string) (string, string) {
+			comp := f["device"]
+			if f["interface"] != "" {
+				comp = f["interface"]
+			}
+			if strings.Contains(f["message"], "CRIT") {
+				return comp, hwSevFatal
+			}
+			return comp, hwSevError
+		},
+	},
+}
+
+// Pull the severity out of an MCE bank status register
+func mceSeverity(status string) string {
+	// NOTE: bit 61 of the status is UC - the error was not corrected,
+	//  bit 57 is PCC - the processor context is corrupt
+	val, err := strconv.ParseUint(status, 16, 64)
+	if err != nil {
+		return hwSevError
+	}
+	switch {
+	case val&(1<<57) != 0:
+		return hwSevFatal
+	case val&(1<<61) != 0:
+		return hwSevUncorrected
+	}
+	return hwSevCorrected
+}
+
+// Read the hardware error configuration from the environment
+func initHwErrors() {
+	readSingleEnvVarInt("HW_ERROR_MAX_RECORDS", &hwErrorMaxRecords, 10, 100000)
+}
+
+// Start keeping errors for a console
+func startHwErrors(xname string) {
+	hwErrorsMutex.Lock()
+	defer hwErrorsMutex.Unlock()
+	if _, ok := nodeErrors[xname]; !ok {
+		nodeErrors[xname] = &nodeHwErrors{lifetime: make(map[string]int)}
+	}
+}
+
+// Drop the errors for a console that is no longer monitored
+func removeHwErrors(xname string) {
+	hwErrorsMutex.Lock()
+	defer hwErrorsMutex.Unlock()
+	delete(nodeErrors, xname)
+}
+
+// Parse a line of console output into a hardware error record
+func parseHwError(xname, text string, when time.Time) (rec HwErrorRecord, detail bool, ok bool) {
+	for _, p := range hwErrorParsers {
+		m := p.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		fields := make(map[string]string)
+		for i, name := range p.re.SubexpNames() {
+			if name != "" && m[i] != "" {
+				fields[name] = m[i]
+			}
+		}
+		comp, sev := p.build(fields)
+		rec = HwErrorRecord{
+			Xname:     xname,
+			Time:      when,
+			Type:      p.errType,
+			Component: comp,
+			Severity:  sev,
+			Fields:    fields,
+			Line:      text,
+		}
+		return rec, p.detail, true
+	}
+	return rec, false, false
+}
+
+// Check a line of console output for hardware errors
+func checkHwErrors(xname, text string, when time.Time) {
+	// lines added by conman itself are not part of the console output
+	if isConmanMessage(text) {
+		return
+	}
+	if ts, rest := splitConmanTimestamp(text); !ts.IsZero() {
+		text = rest
+	}
+
+	// quick filter before running all of the expressions
+	if !strings.Contains(text, "Hardware Error") && !strings.Contains(text, "EDAC") &&
+		!strings.Contains(text, "PCIe Bus Error") && !strings.Contains(text, "nvme") &&
+		!strings.Contains(text, "cxi") {
+		return
+	}
+
+	rec, detail, ok := parseHwError(xname, text, when)
+	if !ok {
+		return
+	}
+	if detail {
+		addHwErrorDetail(xname, rec)
+		return
+	}
+	if rec.Severity == hwSevUncorrected || rec.Severity == hwSevFatal {
+		log.Printf("Hardware error on %s: %s %s %s", xname, rec.Severity, rec.Type, rec.Component)
+	}
+
+	hwErrorsMutex.Lock()
+	defer hwErrorsMutex.Unlock()
+	ne, ok := nodeErrors[xname]
+	if !ok {
+		return
+	}
+	ne.lifetime[rec.Type]++
+	if len(ne.records) < hwErrorMaxRecords {
+		ne.records = append(ne.records, rec)
+	} else {
+		ne.records[ne.next] = rec
+	}
+	ne.next = (ne.next + 1) % hwErrorMaxRecords
+}
+
+// Add the fields of a detail line to the last record of the same type
+func addHwErrorDetail(xname string, rec HwErrorRecord) {
+	hwErrorsMutex.Lock()
+	defer hwErrorsMutex.Unlock()
+	ne, ok := nodeErrors[xname]
+	if !ok || len(ne.records) == 0 {
+		return
+	}
+	last := &ne.records[(ne.next+len(ne.records)-1)%len(ne.records)]
+	if last.Type != rec.Type {
+		return
+	}
+	for k, v := range rec.Fields {
+		last.Fields[k] = v
+	}
+}
+
+// Gather the records for a node since the given time, oldest first
+func hwErrorRecords(xname string, since time.Time) []HwErrorRecord {
+	hwErrorsMutex.Lock()
+	defer hwErrorsMutex.Unlock()
+	ne, ok := nodeErrors[xname]
+	if !ok {
+		return nil
+	}
+	var recs []HwErrorRecord
+	for _, r := range ne.records {
+		if !r.Time.Before(since) {
+			recs = append(recs, r)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Time.Before(recs[j].Time) })
+	return recs
+}
+
+// Roll up the error counts for the given nodes over a window
+func rollupHwErrors(xnames []string, since time.Time, errType string) HwErrorResponse {
+	resp := HwErrorResponse{
+		Since:  since,
+		Totals: make(map[string]int),
+		Nodes:  make(map[string]*HwErrorNodeSummary),
+	}
+	for _, xname := range xnames {
+		hwErrorsMutex.Lock()
+		lifetime := make(map[string]int)
+		if ne, ok := nodeErrors[xname]; ok {
+			for k, v := range ne.lifetime {
+				lifetime[k] = v
+			}
+		}
+		hwErrorsMutex.Unlock()
+
+		summary := &HwErrorNodeSummary{
+			ByType:     make(map[string]int),
+			BySeverity: make(map[string]int),
+			Lifetime:   lifetime,
+		}
+		for _, r := range hwErrorRecords(xname, since) {
+			if errType != "" && r.Type != errType {
+				continue
+			}
+			summary.Total++
+			summary.ByType[r.Type]++
+			summary.BySeverity[r.Severity]++
+			resp.Totals[r.Type]++
+			rec := r
+			summary.LastError = &rec
+		}
+		resp.Nodes[xname] = summary
+	}
+	return resp
+}
+
+// Read the time window for a roll up from the request
+func hwErrorWindow(r *http.Request) (time.Duration, error) {
+	v := r.URL.Query().Get("window")
+	if v == "" {
+		return hwErrorDefaultWindow, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window: %s", v)
+	}
+	return d, nil
+}
+
+// Handle a request for the hardware errors across the monitored nodes
+func doHwErrors(w http.ResponseWriter, r *http.Request) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	window, err := hwErrorWindow(r)
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// only the consoles monitored by this pod are reported
+	xnames := authFilterXnames(r, getCurrNodeXnames())
+	if v := r.URL.Query().Get("xnames"); v != "" {
+		wanted := make(map[string]bool)
+		for _, xn := range strings.Split(v, ",") {
+			wanted[strings.TrimSpace(xn)] = true
+		}
+		var sel []string
+		for _, xn := range xnames {
+			if wanted[xn] {
+				sel = append(sel, xn)
+			}
+		}
+		xnames = sel
+	}
+
+	resp := rollupHwErrors(xnames, time.Now().Add(-window), r.URL.Query().Get("type"))
+	resp.Window = window.String()
+	SendResponseJSON(w, http.StatusOK, resp)
+}
+
+// Handle a request for the hardware error records of a single console
+func doConsoleHwErrors(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	window, err := hwErrorWindow(r)
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recs := hwErrorRecords(xname, time.Now().Add(-window))
+	if recs == nil {
+		recs = []HwErrorRecord{}
+	}
+	SendResponseJSON(w, http.StatusOK, recs)
+}
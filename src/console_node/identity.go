@@ -0,0 +1,227 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the verification that the node printing on a console
+// is the node the console is expected to be connected to
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format of the hostname expected from the NID of a node
+var identityHostnameFormat string = "nid%06d"
+
+// Expressions that pick the node identity out of the console output
+var (
+	// nid000123 login:
+	loginHostRe = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9._-]*) login: *$`)
+	// [root@nid000123 ~]#
+	bracketPromptRe = regexp.MustCompile(`^\[[A-Za-z0-9._-]+@([A-Za-z][A-Za-z0-9._-]*)[ :][^\]]*\][#$]`)
+	// nid000123:~ #
+	slesPromptRe = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*):[~/][^#$]*[#$]( |$)`)
+	// Kernel command line: ... hostname=nid000123 xname=x3000c0s5b0n0 ...
+	cmdlineRe = regexp.MustCompile(`Kernel command line: (.*)$`)
+	// hostnames that carry the nid
+	nidHostRe = regexp.MustCompile(`^nid0*([0-9]+)$`)
+)
+
+// IdentityStatus - what the console output says about the node identity
+type IdentityStatus struct {
+	ExpectedNID      int       `json:"expected_nid"`
+	ExpectedHostname string    `json:"expected_hostname,omitempty"`
+	Hostname         string    `json:"hostname,omitempty"`
+	NID              int       `json:"nid,omitempty"`
+	Xname            string    `json:"xname,omitempty"`
+	Source           string    `json:"source,omitempty"`
+	LastSeen         time.Time `json:"last_seen,omitempty"`
+	Mismatch         bool      `json:"mismatch"`
+	Reason           string    `json:"reason,omitempty"`
+}
+
+// Globals holding the identity of each console
+var identityMutex = &sync.Mutex{}
+var consoleIdentities map[string]*IdentityStatus = make(map[string]*IdentityStatus) // [xname,*IdentityStatus]
+
+// Read the identity configuration from the environment
+func initIdentity() {
+	if val := os.Getenv("IDENTITY_HOSTNAME_FORMAT"); val != "" {
+		identityHostnameFormat = val
+	}
+}
+
+// Set the identity a console is expected to report
+func setExpectedIdentity(node nodeConsoleInfo) {
+	// NOTE: in update config thread with the current nodes locked
+	identityMutex.Lock()
+	defer identityMutex.Unlock()
+	id, ok := consoleIdentities[node.NodeName]
+	if !ok {
+		id = &IdentityStatus{}
+		consoleIdentities[node.NodeName] = id
+	}
+	id.ExpectedNID = node.NID
+	id.ExpectedHostname = ""
+	if node.NID > 0 {
+		id.ExpectedHostname = fmt.Sprintf(identityHostnameFormat, node.NID)
+	}
+}
+
+// Drop the identity of a console that is no longer monitored
+func removeIdentity(xname string) {
+	identityMutex.Lock()
+	defer identityMutex.Unlock()
+	delete(consoleIdentities, xname)
+}
+
+// Get the identity of a console
+func getIdentity(xname string) (IdentityStatus, bool) {
+	identityMutex.Lock()
+	defer identityMutex.Unlock()
+	if id, ok := consoleIdentities[xname]; ok {
+		return *id, true
+	}
+	return IdentityStatus{}, false
+}
+
+// Pull any hostname, nid, or xname out of a line of console output
+func parseIdentity(text string) (hostname string, nid int, xname string, source string) {
+	if m := cmdlineRe.FindStringSubmatch(text); m != nil {
+		for _, arg := range strings.Fields(m[1]) {
+			k, v, _ := strings.Cut(arg, "=")
+			switch k {
+			case "hostname":
+				hostname = v
+			case "xname":
+				xname = v
+			case "nid":
+				nid, _ = strconv.Atoi(v)
+			}
+		}
+		source = "cmdline"
+	} else if m := loginHostRe.FindStringSubmatch(text); m != nil {
+		hostname, source = m[1], "login"
+	} else if m := bracketPromptRe.FindStringSubmatch(text); m != nil {
+		hostname, source = m[1], "prompt"
+	} else if m := slesPromptRe.FindStringSubmatch(text); m != nil {
+		hostname, source = m[1], "prompt"
+	} else {
+		return "", 0, "", ""
+	}
+
+	// only the short name is compared
+	hostname, _, _ = strings.Cut(hostname, ".")
+	if nid == 0 {
+		if m := nidHostRe.FindStringSubmatch(hostname); m != nil {
+			nid, _ = strconv.Atoi(m[1])
+		}
+	}
+	return hostname, nid, xname, source
+}
+
+// Compare the identity in a line of console output with what is expected
+func checkIdentity(xname, text string, when time.Time) {
+	// lines added by conman itself are not part of the console output
+	if isConmanMessage(text) {
+		return
+	}
+	if ts, rest := splitConmanTimestamp(text); !ts.IsZero() {
+		text = rest
+	}
+
+	// quick filter before running all of the expressions
+	if !strings.Contains(text, "login:") && !strings.Contains(text, "#") &&
+		!strings.Contains(text, "$") && !strings.Contains(text, "Kernel command line") {
+		return
+	}
+	hostname, nid, seenXname, source := parseIdentity(text)
+	if source == "" || (hostname == "" && nid == 0 && seenXname == "") {
+		return
+	}
+
+	identityMutex.Lock()
+	id, ok := consoleIdentities[xname]
+	if !ok {
+		identityMutex.Unlock()
+		return
+	}
+	if hostname != "" {
+		id.Hostname = hostname
+	}
+	if nid != 0 {
+		id.NID = nid
+	}
+	if source == "cmdline" {
+		// the xname only shows up once per boot so keep it until the next one
+		id.Xname = seenXname
+	}
+	id.Source = source
+	id.LastSeen = when
+
+	// figure out if what was seen agrees with what is expected
+	reason := ""
+	verified := false
+	switch {
+	case id.Xname != "" && id.Xname != xname:
+		reason = fmt.Sprintf("console %s reports xname %s", xname, id.Xname)
+	case nid != 0 && id.ExpectedNID > 0 && nid != id.ExpectedNID:
+		reason = fmt.Sprintf("console %s reports hostname %s with NID %d, expected NID %d (%s)",
+			xname, hostname, nid, id.ExpectedNID, id.ExpectedHostname)
+	case seenXname != "" && seenXname == xname,
+		nid != 0 && nid == id.ExpectedNID,
+		hostname != "" && strings.EqualFold(hostname, id.ExpectedHostname):
+		verified = true
+	}
+	if reason == "" && !verified {
+		// NOTE: a line like '[root@localhost ~]#' says nothing about which node
+		//  this is, so keep the current state rather than clear a mismatch
+		identityMutex.Unlock()
+		return
+	}
+	wasMismatch, oldReason := id.Mismatch, id.Reason
+	id.Mismatch = reason != ""
+	id.Reason = reason
+	details := map[string]string{
+		"hostname":     id.Hostname,
+		"nid":          strconv.Itoa(id.NID),
+		"expected_nid": strconv.Itoa(id.ExpectedNID),
+		"source":       source,
+	}
+	identityMutex.Unlock()
+
+	// only raise events when the state changes
+	if reason != "" && reason != oldReason {
+		raiseEvent(xname, "identity_mismatch", eventWarning, reason, details)
+	} else if reason == "" && wasMismatch {
+		raiseEvent(xname, "identity_verified", eventInfo,
+			fmt.Sprintf("console %s now reports the expected identity", xname), details)
+	}
+}
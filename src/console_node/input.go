@@ -0,0 +1,187 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains sending input, serial breaks, and SysRq keys to a console
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Modes of console input
+const (
+	inputText  string = "text"  // text sent as is
+	inputRaw   string = "raw"   // base64 encoded bytes
+	inputBreak string = "break" // serial break
+	inputSysRq string = "sysrq" // serial break followed by a SysRq key
+)
+
+// Conman client escape that sends a serial break to the console
+const conmanEscapeBreak string = "&B"
+
+// Largest amount of input accepted in a single request
+const maxConsoleInput int = 4096
+
+// Keys accepted for a SysRq request
+const sysrqKeys string = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// ConsoleInputRequest - input to send to a console
+type ConsoleInputRequest struct {
+	Mode string `json:"mode"`
+	Data string `json:"data,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+// ConsoleInputResponse - what was sent to a console
+type ConsoleInputResponse struct {
+	Xname string `json:"xname"`
+	Mode  string `json:"mode"`
+	Bytes int    `json:"bytes"`
+}
+
+// Build the conman client input for a request
+func buildConsoleInput(req ConsoleInputRequest) (input string, nbytes int, err error) {
+	switch req.Mode {
+	case inputText:
+		if req.Data == "" {
+			return "", 0, fmt.Errorf("no data to send")
+		}
+		return escapeConmanInput(req.Data), len(req.Data), nil
+	case inputRaw:
+		data, err := base64.StdEncoding.DecodeString(req.Data)
+		if err != nil {
+			return "", 0, fmt.Errorf("raw data must be base64 encoded: %s", err)
+		}
+		if len(data) == 0 {
+			return "", 0, fmt.Errorf("no data to send")
+		}
+		return escapeConmanInput(string(data)), len(data), nil
+	case inputBreak:
+		return conmanEscapeBreak, 0, nil
+	case inputSysRq:
+		if len(req.Key) != 1 || !strings.Contains(sysrqKeys, strings.ToLower(req.Key)) {
+			return "", 0, fmt.Errorf("invalid SysRq key: %q", req.Key)
+		}
+		// NOTE: the kernel takes the first key after a break as the SysRq command
+		return conmanEscapeBreak + escapeConmanInput(strings.ToLower(req.Key)), 1, nil
+	}
+	return "", 0, fmt.Errorf("unknown input mode: %q", req.Mode)
+}
+
+// Describe input for the pod log and events without any of its contents
+func describeConsoleInput(req ConsoleInputRequest, nbytes int) string {
+	// NOTE: typed text may hold passwords, only the opt-in input audit records it
+	switch req.Mode {
+	case inputBreak:
+		return "serial break"
+	case inputSysRq:
+		return "SysRq " + strings.ToLower(req.Key)
+	case inputText:
+		return fmt.Sprintf("%d bytes of text", nbytes)
+	}
+	return fmt.Sprintf("%d bytes of raw input", nbytes)
+}
+
+// Shorten data put in an audit record
+func truncateForAudit(s string) string {
+	const maxLen = 64
+	if len(s) > maxLen {
+		return s[:maxLen] + "..."
+	}
+	return s
+}
+
+// Find who sent a request
+func requestUser(r *http.Request) string {
+	if c := requestClaims(r); c != nil {
+		return c.User
+	}
+	// NOTE: without a token the user is only taken from a trusted api gateway,
+	// anyone else could set these headers
+	if fromTrustedProxy(r) {
+		for _, h := range []string{"X-Remote-User", "X-Forwarded-User"} {
+			if v := r.Header.Get(h); v != "" {
+				return v
+			}
+		}
+	}
+	return "anonymous"
+}
+
+// Handle a request to send input to a console
+func doConsoleInput(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'POST' calls
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(2*maxConsoleInput)))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unable to read request: %s", err))
+		return
+	}
+	var req ConsoleInputRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %s", err))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = inputText
+	}
+	input, nbytes, err := buildConsoleInput(req)
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if nbytes > maxConsoleInput {
+		sendJSONError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Input of %d bytes is over the limit of %d", nbytes, maxConsoleInput))
+		return
+	}
+
+	// record who sent what before it goes to the console
+	user := requestUser(r)
+	what := describeConsoleInput(req, nbytes)
+	log.Printf("Console input to %s from %s (%s): %s", xname, user, r.RemoteAddr, what)
+	raiseEvent(xname, "console_input", eventInfo,
+		fmt.Sprintf("%s sent %s to console %s", user, what, xname),
+		map[string]string{"user": user, "remote": r.RemoteAddr, "mode": req.Mode})
+	auditConsoleInput(r, xname, req.Mode, req.Data+req.Key)
+
+	if err := sendConmanInput(xname, input); err != nil {
+		sendJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	SendResponseJSON(w, http.StatusOK, ConsoleInputResponse{Xname: xname, Mode: req.Mode, Bytes: nbytes})
+}
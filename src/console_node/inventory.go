@@ -0,0 +1,259 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the static inventory used in standalone mode
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory file of the consoles when running without console-data and
+// console-operator, empty when running as part of the console services
+var inventoryFile string = ""
+
+// Last problem reported with the inventory file
+var inventoryError string = ""
+
+// Node xnames end with the node number under the bmc
+var nodeXnameRe = regexp.MustCompile(`^(.+b[0-9]+)n[0-9]+$`)
+
+// inventoryNode - a console as described in the inventory file
+type inventoryNode struct {
+	Xname   string `json:"xname" yaml:"xname"`
+	BmcName string `json:"bmcname" yaml:"bmcname"`
+	BmcFqdn string `json:"bmcfqdn" yaml:"bmcfqdn"`
+	Class   string `json:"class" yaml:"class"`
+	NID     int    `json:"nid" yaml:"nid"`
+	Role    string `json:"role" yaml:"role"`
+	Tenant  string `json:"tenant" yaml:"tenant"`
+}
+
+// Check if running standalone from a static inventory
+func standaloneMode() bool {
+	return inventoryFile != ""
+}
+
+// Read and check the inventory file
+func readInventory(fileName string) ([]nodeConsoleInfo, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseInventory(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inventory file %s: %s", fileName, err)
+	}
+
+	nodes := make([]nodeConsoleInfo, 0, len(entries))
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		node, err := e.consoleInfo()
+		if err != nil {
+			return nil, fmt.Errorf("invalid inventory file %s entry %d: %s", fileName, i+1, err)
+		}
+		if seen[node.NodeName] {
+			return nil, fmt.Errorf("invalid inventory file %s: %s is listed more than once", fileName, node.NodeName)
+		}
+		seen[node.NodeName] = true
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// Parse an inventory, JSON or YAML, either a list of nodes or a mapping with
+// the list under 'nodes'
+func parseInventory(data []byte) ([]inventoryNode, error) {
+	var entries []inventoryNode
+	var doc struct {
+		Nodes []inventoryNode `json:"nodes" yaml:"nodes"`
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		return doc.Nodes, nil
+	}
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	// look at the top of the document to pick the layout
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) > 0 && root.Content[0].Kind == yaml.SequenceNode {
+		err := decodeYaml(data, &entries)
+		return entries, err
+	}
+	err := decodeYaml(data, &doc)
+	return doc.Nodes, err
+}
+
+// Fill in the defaults of an inventory entry and check it can be used
+func (e inventoryNode) consoleInfo() (nodeConsoleInfo, error) {
+	node := nodeConsoleInfo{
+		NodeName: e.Xname,
+		BmcName:  e.BmcName,
+		BmcFqdn:  e.BmcFqdn,
+		Class:    e.Class,
+		NID:      e.NID,
+		Role:     e.Role,
+		Tenant:   e.Tenant,
+	}
+	if node.NodeName == "" {
+		return node, fmt.Errorf("missing xname")
+	}
+	if node.BmcName == "" {
+		m := nodeXnameRe.FindStringSubmatch(node.NodeName)
+		if m == nil {
+			return node, fmt.Errorf("no bmcname given for %s", node.NodeName)
+		}
+		node.BmcName = m[1]
+	}
+	if node.BmcFqdn == "" {
+		node.BmcFqdn = node.BmcName
+	}
+	if !node.isRiver() && !node.isMountain() && !node.isParadise() {
+		return node, fmt.Errorf("unknown class %q for %s", node.Class, node.NodeName)
+	}
+	if node.Role == "" {
+		node.Role = "Compute"
+	}
+	return node, nil
+}
+
+// Make the current nodes match the inventory file
+func syncInventory() {
+	// NOTE: in watchInventory thread, this takes the place of doGetNewNodes
+
+	// if the pod is shutting down, don't touch the current nodes
+	if inShutdown {
+		log.Print("In shutdown, skipping syncInventory")
+		return
+	}
+
+	nodes, err := readInventory(inventoryFile)
+	if err != nil {
+		// keep the consoles already configured until the file is fixed
+		log.Printf("Unable to read the inventory: %s", err)
+		if err.Error() != inventoryError {
+			raiseEvent("", "inventory_invalid", eventWarning,
+				fmt.Sprintf("inventory not loaded: %s", err),
+				map[string]string{"file": inventoryFile})
+			inventoryError = err.Error()
+		}
+		return
+	}
+	inventoryError = ""
+	assignTenants(nodes)
+
+	currNodesMutex.Lock()
+	defer currNodesMutex.Unlock()
+
+	// drop the consoles no longer listed, or listed with different settings
+	changed := false
+	wanted := make(map[string]*nodeConsoleInfo)
+	for i := range nodes {
+		wanted[nodes[i].NodeName] = &nodes[i]
+	}
+	numDropped := 0
+	allNodes := [3](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes, &currentMtnNodes}
+	for _, ar := range allNodes {
+		for key, ni := range *ar {
+			if w, ok := wanted[key]; !ok || *w != *ni {
+				delete(*ar, key)
+				stopTailing(key)
+				changed = true
+				numDropped++
+			}
+		}
+	}
+
+	// add the consoles not yet configured
+	newRvr := 0
+	newMtn := 0
+	newPds := 0
+	for _, node := range wanted {
+		if node.isRiver() {
+			if _, ok := currentRvrNodes[node.NodeName]; !ok {
+				currentRvrNodes[node.NodeName] = node
+				changed = true
+				newRvr++
+			}
+		} else if node.isMountain() {
+			if _, ok := currentMtnNodes[node.NodeName]; !ok {
+				currentMtnNodes[node.NodeName] = node
+				changed = true
+				newMtn++
+			}
+		} else if node.isParadise() {
+			if _, ok := currentPdsNodes[node.NodeName]; !ok {
+				currentPdsNodes[node.NodeName] = node
+				changed = true
+				newPds++
+			}
+		}
+	}
+
+	// everything in the inventory is the target of this instance
+	targetRvrNodes = len(currentRvrNodes)
+	targetMtnNodes = len(currentMtnNodes) + len(currentPdsNodes)
+
+	if changed {
+		log.Printf("Inventory added River:%d, Mountain:%d, Paradise:%d, dropped:%d",
+			newRvr, newMtn, newPds, numDropped)
+
+		// trigger a re-configuration and restart of conman
+		signalConmanTERM()
+
+		// rebuild the log rotation configuration file
+		updateLogRotateConf()
+	}
+}
+
+// Primary loop to watch the inventory file for changes
+func watchInventory() {
+	log.Printf("Running standalone from the inventory in %s", inventoryFile)
+	for {
+		syncInventory()
+
+		// Wait for the correct polling interval
+		time.Sleep(time.Duration(newNodeLookupSec) * time.Second)
+	}
+}
@@ -0,0 +1,118 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the tests of reading the inventory file
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInventory(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []inventoryNode
+		wantErr bool
+	}{
+		{
+			name: "yaml nodes",
+			data: `nodes:
+  - xname: x3000c0s1b0n0
+    bmcfqdn: x3000c0s1b0.lab.example.com
+    class: River
+    nid: 1
+    role: Compute
+  - xname: x1000c0s0b0n0
+    class: Mountain
+    nid: 1000
+`,
+			want: []inventoryNode{
+				{Xname: "x3000c0s1b0n0", BmcFqdn: "x3000c0s1b0.lab.example.com", Class: "River", NID: 1, Role: "Compute"},
+				{Xname: "x1000c0s0b0n0", Class: "Mountain", NID: 1000},
+			},
+		},
+		{
+			name: "yaml list",
+			data: "# lab\n- xname: x3000c0s1b0n0 # first\n  class: River\n",
+			want: []inventoryNode{{Xname: "x3000c0s1b0n0", Class: "River"}},
+		},
+		{
+			name: "yaml quoted hash",
+			data: "nodes:\n  - xname: x3000c0s1b0n0\n    class: River\n    tenant: \"team #1\"\n",
+			want: []inventoryNode{{Xname: "x3000c0s1b0n0", Class: "River", Tenant: "team #1"}},
+		},
+		{
+			name: "json nodes",
+			data: `{"nodes": [{"xname": "x3000c0s1b0n0", "class": "River", "nid": 1}]}`,
+			want: []inventoryNode{{Xname: "x3000c0s1b0n0", Class: "River", NID: 1}},
+		},
+		{
+			name: "json list",
+			data: `[{"xname": "x3000c0s1b0n0", "bmcname": "x3000c0s1b0", "class": "River"}]`,
+			want: []inventoryNode{{Xname: "x3000c0s1b0n0", BmcName: "x3000c0s1b0", Class: "River"}},
+		},
+		{
+			name: "empty",
+			data: "# no consoles yet\n",
+		},
+		{
+			name:    "unknown key",
+			data:    "nodes:\n  - xname: x3000c0s1b0n0\n    clas: River\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid nid",
+			data:    "nodes:\n  - xname: x3000c0s1b0n0\n    nid: one\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad indent",
+			data:    "nodes:\n  - xname: x3000c0s1b0n0\n   class: River\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInventory([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2020-2022, 2024 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2020-2022, 2024, 2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -28,30 +28,47 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/hpcloud/tail"
 )
 
-// Global vars
-var conAggMutex = &sync.Mutex{}
-var conAggLogger *log.Logger = nil
-
 // Globals to build up the aggregation file name for this pod
 const conAggLogFileBase string = "/tmp/consoleAgg/consoleAgg-"
 
 var conAggLogFile string = ""
 
+// Directory of the aggregation logs, known before the pod name is
+var conAggLogDir string = filepath.Dir(conAggLogFileBase)
+
+// Add the time each line was received to the aggregation log
+var aggLogTimestamps bool = false
+
+// Directory conman writes the console log files to
+const consoleLogDir string = "/var/log/conman"
+
+// Time between looks at the unfinished line at the end of a console log
+var promptCheckInterval time.Duration = 2 * time.Second
+
+// Longest unfinished line that is checked for a prompt
+const maxPromptLen int64 = 512
+
 // map to cancel threads tailing log files
 var tailThreads map[string]*context.CancelFunc = make(map[string]*context.CancelFunc)
 
+// Name of the console log file for a node
+func consoleLogFile(xname string) string {
+	return fmt.Sprintf("%s/console.%s", tenantDir(consoleLogDir, xname), xname)
+}
+
 // Set up tailing a log file to add to the aggregation file
 func aggregateFile(xname string) bool {
 	// NOTE: in update config thread
@@ -64,6 +81,27 @@ func aggregateFile(xname string) bool {
 		ctx, cancel := context.WithCancel(context.Background())
 		tailThreads[xname] = &cancel
 
+		// keep a virtual screen of the console output
+		startScreen(xname)
+
+		// keep a timed recording of the console output
+		startRecording(xname)
+
+		// keep track of hardware errors reported on the console
+		startHwErrors(xname)
+
+		// follow the boot progress of the node
+		startBootTracking(xname)
+
+		// watch for the console going silent
+		startConsoleActivity(xname)
+
+		// hold the console to its output budget
+		startFloodLimiter(xname)
+
+		// check the ownership of the console files
+		startLogPolicy(xname)
+
 		// record being tracked and forward log file contents
 		go watchConsoleLogFile(ctx, xname)
 	}
@@ -105,6 +143,30 @@ func stopTailing(xname string) {
 
 		// remove from map
 		delete(tailThreads, xname)
+
+		// the screen is no longer being updated
+		removeScreen(xname)
+
+		// finish off the recording
+		stopRecording(xname)
+
+		// the hardware errors are no longer tracked
+		removeHwErrors(xname)
+
+		// the boot progress is no longer followed
+		removeBootTracking(xname)
+
+		// the identity is no longer checked
+		removeIdentity(xname)
+
+		// the console activity is no longer watched
+		removeConsoleActivity(xname)
+
+		// the output budget is no longer needed
+		removeFloodLimiter(xname)
+
+		// the console files are no longer checked
+		removeLogPolicy(xname)
 	} else {
 		log.Printf("Stop tailing: could not find %s in tailThreads map", xname)
 	}
@@ -120,14 +182,29 @@ func watchConsoleLogFile(ctx context.Context, xname string) {
 		ReOpen:    true,  // if the files is deleted or moved, reopen original file
 		MustExist: false, // if file doesn't exist keep trying
 		Poll:      true,  // NOTE: it looks like file events don't work - poll instead
-		Logger:    tail.DiscardingLogger,
-		Location:  &tail.SeekInfo{Offset: 0, Whence: 2}, // set to open at the current end of file
 	}
 
 	// full path to the file
-	filename := fmt.Sprintf("/var/log/conman/console.%s", xname)
+	filename := consoleLogFile(xname)
 	log.Printf("Starting to parse file: %s", filename)
 
+	// fill in the screen with what is already present in the file
+	seedScreen(xname, filename)
+
+	// start at the current end of the file so the offset of each line is known
+	var offset int64 = 0
+	if fi, err := os.Stat(filename); err == nil {
+		offset = fi.Size()
+	}
+	conf.Location = &tail.SeekInfo{Offset: offset, Whence: 0}
+
+	// watch for the file being rotated out from under the tail
+	tracker := newTailFileTracker(filename)
+
+	// keep the time index of the file up to date
+	idx := openTimeIndex(consoleIndexFile(xname))
+	defer idx.close()
+
 	// start the tail operation
 	tf, err := tail.TailFile(filename, conf)
 	if err != nil {
@@ -135,6 +212,13 @@ func watchConsoleLogFile(ctx context.Context, xname string) {
 		return
 	}
 
+	// prompts are written without a newline so the tail does not pass them on,
+	// look at the end of the file for them
+	promptTicker := time.NewTicker(promptCheckInterval)
+	defer promptTicker.Stop()
+	var lastPromptAt int64 = -1
+	lastPrompt := ""
+
 	// parse the lines of the tail output while looking for a cancel signal
 	for {
 		select {
@@ -150,48 +234,149 @@ func watchConsoleLogFile(ctx context.Context, xname string) {
 			tf.Stop()
 			return
 		case line := <-tf.Lines:
-			// output the line from the channel
-			writeToAggLog(fmt.Sprintf("console.hostname: %s %s", xname, line.Text))
+			// if the file was rotated the offsets start over in the new file
+			if tracker.changed(offset) {
+				offset = 0
+				idx.rotate(func(num int) string { return rotatedConsoleIndexFile(xname, num) })
+				shiftRotatedFiles(consoleMarkFile(xname), func(num int) string { return rotatedConsoleMarkFile(xname, num) },
+					logRotConNumRotate)
+			}
+			idx.add(line.Time, offset)
+			offset += int64(len(line.Text)) + 1
+
+			// hand the line to everything that consumes console output
+			processConsoleLine(xname, line.Text, line.Time)
+		case <-promptTicker.C:
+			// only pass on each unfinished line once
+			text, at := readPartialLine(filename, offset)
+			if text != "" && (at != lastPromptAt || text != lastPrompt) {
+				lastPromptAt, lastPrompt = at, text
+				processConsolePrompt(xname, text, time.Now())
+			}
 		}
 	}
 }
 
-// function to manage concurrent writes to the aggregation log
-func writeToAggLog(str string) {
-	conAggMutex.Lock()
-	defer conAggMutex.Unlock()
-	if conAggLogger != nil {
-		conAggLogger.Printf("%s", str)
+// Read the unfinished line at the end of a console log, starting no earlier
+// than the given offset, returns the line and where it starts
+func readPartialLine(filename string, from int64) (string, int64) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", 0
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.Size() <= from {
+		return "", 0
+	}
+	start := from
+	if fi.Size()-start > maxPromptLen {
+		start = fi.Size() - maxPromptLen
+	}
+	buf := make([]byte, fi.Size()-start)
+	if _, err := f.ReadAt(buf, start); err != nil {
+		return "", 0
 	}
+	nl := bytes.LastIndexByte(buf, '\n')
+	if nl < 0 && start > from {
+		// too long to be a prompt
+		return "", 0
+	}
+	return string(buf[nl+1:]), start + int64(nl+1)
 }
 
-// Function to close/open a new aggregation logger
-func respinAggLog() {
-	// when the file changes due to log rotation we must recreate the logger
-	conAggMutex.Lock()
-	defer conAggMutex.Unlock()
+// Pass the unfinished line at the end of a console log to the consumers that
+// look for prompts
+func processConsolePrompt(xname, text string, when time.Time) {
+	text = strings.TrimRight(text, "\r")
 
-	// make sure the directory exists to put the file in place
-	pos := strings.LastIndex(conAggLogFile, "/")
-	if pos < 0 {
-		log.Printf("Error: console log aggregation file name: %s", conAggLogFile)
-		return
+	// the login prompt marks the end of a boot
+	trackBootProgress(xname, text, when)
+
+	// the login and shell prompts carry the hostname
+	checkIdentity(xname, text, when)
+}
+
+// Distribute a single line of console output to all consumers
+func processConsoleLine(xname, text string, when time.Time) {
+	// consoles end their lines with '\r\n', only the newline is removed by the tail
+	text = strings.TrimRight(text, "\r")
+
+	// note the console is producing output
+	noteConsoleActivity(xname, text, when)
+
+	// record clients attaching to and leaving the console
+	checkAuditSession(xname, text, when)
+
+	// keep a flooding console from swamping the aggregation log and streams
+	allow, summary := floodCheck(xname, text, when)
+	if summary != "" {
+		writeFloodSummary(xname, summary, when)
 	}
-	conAggLogDir := conAggLogFile[:pos]
-	if _, err := ensureDirPresent(conAggLogDir, 0766); err != nil {
-		log.Printf("Failed to respin aggregation file: %s", err)
-		return
+	if allow {
+		// add to the aggregation log
+		writeConsoleToAggLog(xname, text, when)
+
+		// add to the console recording
+		recordConsoleLine(xname, text, when)
 	}
 
-	log.Printf("Respinning aggregation log")
-	calf, err := os.OpenFile(conAggLogFile, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0600)
-	if err != nil {
-		log.Printf("Could not open console aggregate log file: %s", err)
+	// update the virtual screen of the console
+	feedScreen(xname, text)
+
+	// pick out any hardware errors
+	checkHwErrors(xname, text, when)
+
+	// follow the boot progress of the node
+	trackBootProgress(xname, text, when)
+
+	// make sure the node is the one expected on this console
+	checkIdentity(xname, text, when)
+}
+
+// Add a line of console output to the aggregation log
+func writeConsoleToAggLog(xname, text string, when time.Time) {
+	if aggLogTimestamps {
+		writeToTenantAggLog(consoleTenant(xname), fmt.Sprintf("console.hostname: %s %s %s", xname, when.Format(time.RFC3339Nano), text))
 	} else {
-		log.Printf("Restarted aggregation log file: %s", conAggLogFile)
-		conAggLogger = log.New(calf, "", 0)
-		conAggLogger.Print("Starting aggregation log")
+		writeToTenantAggLog(consoleTenant(xname), fmt.Sprintf("console.hostname: %s %s", xname, text))
+	}
+}
+
+// Lines conman adds to the console log itself start with this tag
+const conmanMsgPrefix string = "<ConMan>"
+
+// Check if a console log line was written by conman rather than the console
+func isConmanMessage(text string) bool {
+	return strings.HasPrefix(text, conmanMsgPrefix)
+}
+
+// Layout of the timestamp conman adds to each line with 'logopts=timestamp'
+const conmanTimestampLayout string = "2006-01-02 15:04:05"
+
+// Split off the timestamp conman may have put at the start of a line
+func splitConmanTimestamp(text string) (time.Time, string) {
+	// NOTE: the timestamp may or may not be in brackets, handle both
+	rest := text
+	bracketed := strings.HasPrefix(rest, "[")
+	if bracketed {
+		rest = rest[1:]
+	}
+	if len(rest) < len(conmanTimestampLayout) {
+		return time.Time{}, text
+	}
+	ts, err := time.ParseInLocation(conmanTimestampLayout, rest[:len(conmanTimestampLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, text
+	}
+	rest = rest[len(conmanTimestampLayout):]
+	if bracketed {
+		if !strings.HasPrefix(rest, "]") {
+			return time.Time{}, text
+		}
+		rest = rest[1:]
 	}
+	return ts, strings.TrimPrefix(rest, " ")
 }
 
 // Take the output of the pipe and log it
@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2021-2023, 2025 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2021-2023, 2025-2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -61,7 +61,7 @@ var logRotAggNumRotate int = 1       // number of aggregation backup copies to k
 // Initialize and start log rotation
 func logRotate() {
 	// Set up the 'backups' directory for logrotation to use
-	ensureDirPresent(logRotDir, 0755)
+	ensureDirPresent(logRotDir, logDirMode)
 
 	// Check for log rotation env vars
 	if val := os.Getenv("LOG_ROTATE_ENABLE"); val != "" {
@@ -194,12 +194,24 @@ func updateLogRotateConf() {
 	}
 
 	// Add all nodes
-	consoleLogBackupDir := "/var/log/conman.old"
+	// NOTE: the consoles of tenants are rotated into their own backup directories
+	tenants := make(map[string]bool)
 	allNodes := [3](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes, &currentMtnNodes}
 	for _, ar := range allNodes {
-		for xname := range *ar {
-			fn := fmt.Sprintf("/var/log/conman/console.%s", xname)
-			writeConfigEntry(lrf, fn, consoleLogBackupDir, logRotConNumRotate, logRotConFileSize)
+		for xname, ni := range *ar {
+			fn := consoleLogFile(xname)
+			writeConfigEntry(lrf, fn, tenantDir(logRotDir, xname), logRotConNumRotate, logRotConFileSize)
+			if ni.Tenant != "" {
+				tenants[ni.Tenant] = true
+			}
+		}
+	}
+
+	// Add the aggregation files of the tenants
+	if conAggLogFile != "" {
+		for tenant := range tenants {
+			fn := tenantAggLogFile(tenant)
+			writeConfigEntry(lrf, fn, filepath.Dir(fn), logRotAggNumRotate, logRotAggFileSize)
 		}
 	}
 
@@ -226,47 +238,45 @@ func writeConfigEntry(lrf *os.File, fileName string, oldDir string, numRotate in
 func parseTimestamp(line string) (string, time.Time, bool, bool) {
 	// NOTE: we are expecting a line in the format of:
 	//  "/var/log/conman/console.xname" YYYY-MM-DD-HH-MM-SS
+	//  the consoles of tenants are in /var/log/conman/tenants/tenant/console.xname
 	var nodeName string
 	var fd time.Time
 	isCon := false
 	isAgg := false
 
-	// if the line does not have a valid console log name, skip
-	const filePrefix string = "/var/log/conman/console."
-	timeStampStr := ""
-	pos := strings.Index(line, filePrefix)
-	nodeStPos := 0
-	if pos != -1 {
-		// found a node log file - pull out the node name and time stamp string
-		nodeStPos = pos + len(filePrefix)
-
-		// pull out the node name
-		posQ2 := strings.Index(line[nodeStPos:], "\"")
-		if posQ2 == -1 {
-			// unexpected - should be a " char at the end of the filename
-			log.Printf("  Unexpected file format - expected quote to close filename")
-			return nodeName, fd, isCon, isAgg
-		}
+	// pull out the file name and time stamp string
+	posQ1 := strings.Index(line, "\"")
+	if posQ1 == -1 {
+		// no log files on this line
+		return nodeName, fd, isCon, isAgg
+	}
+	posQ2 := strings.Index(line[posQ1+1:], "\"")
+	if posQ2 == -1 {
+		// unexpected - should be a " char at the end of the filename
+		log.Printf("  Unexpected file format - expected quote to close filename")
+		return nodeName, fd, isCon, isAgg
+	}
+	posQ2 += posQ1 + 1
+	fileName := line[posQ1+1 : posQ2]
+	if posQ2+2 > len(line) {
+		return nodeName, fd, isCon, isAgg
+	}
+	timeStampStr := line[posQ2+2:]
 
-		// reindex for position in entire line and split
-		posQ2 += nodeStPos
-		nodeName = line[nodeStPos:posQ2]
-		timeStampStr = line[posQ2+2:]
+	if strings.HasPrefix(fileName, consoleLogDir+"/") && strings.HasPrefix(filepath.Base(fileName), "console.") {
+		// found a node log file, keyed by the full name as tenants have their own directories
+		nodeName = fileName
 		isCon = true
-	} else {
-		// see if this is the console aggregation log file
-		pos = strings.Index(line, conAggLogFile)
-		if pos == -1 {
-			// no log files on this line
-			return nodeName, fd, isCon, isAgg
-		}
-
-		// we are dealing with the console aggregation log
+	} else if conAggLogFile != "" && filepath.Base(fileName) == filepath.Base(conAggLogFile) {
+		// we are dealing with one of the console aggregation logs
 		nodeName = "consoleAgg.log"
+		if fileName != conAggLogFile {
+			nodeName = fileName
+		}
 		isAgg = true
-
-		// pull out the position of the timestamp
-		timeStampStr = line[len(conAggLogFile)+pos+2:]
+	} else {
+		// no log files on this line
+		return nodeName, fd, isCon, isAgg
 	}
 
 	// process the line
@@ -0,0 +1,122 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the metrics endpoint in the prometheus text format
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Writer for metrics in the prometheus text exposition format
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+// Write the help and type lines of a metric
+func (mw *metricsWriter) header(name, mtype, help string) {
+	fmt.Fprintf(&mw.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, mtype)
+}
+
+// Write a single value of a metric with label name/value pairs
+func (mw *metricsWriter) value(name string, val float64, labels ...string) {
+	mw.buf.WriteString(name)
+	if len(labels) > 1 {
+		mw.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				mw.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&mw.buf, "%s=%s", labels[i], strconv.Quote(labels[i+1]))
+		}
+		mw.buf.WriteByte('}')
+	}
+	mw.buf.WriteByte(' ')
+	mw.buf.WriteString(strconv.FormatFloat(val, 'g', -1, 64))
+	mw.buf.WriteByte('\n')
+}
+
+// Write out the metrics on the consoles being monitored
+func writeConsoleMetrics(mw *metricsWriter) {
+	currNodesMutex.Lock()
+	numRvr, numMtn, numPds := len(currentRvrNodes), len(currentMtnNodes), len(currentPdsNodes)
+	currNodesMutex.Unlock()
+
+	mw.header("console_node_consoles", "gauge", "Number of consoles monitored by this pod")
+	mw.value("console_node_consoles", float64(numRvr), "pod", podName, "class", "river")
+	mw.value("console_node_consoles", float64(numMtn), "pod", podName, "class", "mountain")
+	mw.value("console_node_consoles", float64(numPds), "pod", podName, "class", "paradise")
+}
+
+// Write out the hardware error counters
+func writeHwErrorMetrics(mw *metricsWriter) {
+	hwErrorsMutex.Lock()
+	defer hwErrorsMutex.Unlock()
+
+	xnames := make([]string, 0, len(nodeErrors))
+	for xname := range nodeErrors {
+		xnames = append(xnames, xname)
+	}
+	sort.Strings(xnames)
+
+	mw.header("console_node_hw_errors_total", "counter", "Hardware errors seen on the node console")
+	for _, xname := range xnames {
+		types := make([]string, 0, len(nodeErrors[xname].lifetime))
+		for t := range nodeErrors[xname].lifetime {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			mw.value("console_node_hw_errors_total", float64(nodeErrors[xname].lifetime[t]), "xname", xname, "type", t)
+		}
+	}
+}
+
+// Handle a request for the metrics
+func doMetrics(w http.ResponseWriter, r *http.Request) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	mw := &metricsWriter{}
+	writeConsoleMetrics(mw)
+	writeAggWriterMetrics(mw)
+	writeHwErrorMetrics(mw)
+	writeBootMetrics(mw)
+	writeConsoleHealthMetrics(mw)
+	writeFloodMetrics(mw)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write(mw.buf.Bytes())
+}
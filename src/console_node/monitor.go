@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2023-2025 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2023-2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -27,9 +27,7 @@
 package main
 
 import (
-	"fmt"
 	"log"
-	"os"
 	"time"
 
 	compcreds "github.com/Cray-HPE/hms-compcredentials"
@@ -58,8 +56,13 @@ func checkForChanges() {
 		restartConman = true
 	}
 
-	// make sure that the log files still have the correct permissions
-	checkLogFiles()
+	// check for consoles that moved tenant or were held back without one
+	if checkIfTenantsChanged() {
+		restartConman = true
+	}
+
+	// make sure that the log files still follow the ownership policy
+	enforceLogPolicy()
 
 	//restart conman if necessary
 	if restartConman {
@@ -67,41 +70,6 @@ func checkForChanges() {
 	}
 }
 
-// function to check a single file or directory
-func checkItemPermissions(itemName string, mode os.FileMode) {
-	fs, err := os.Stat(itemName)
-	if err != nil {
-		log.Printf("    Error getting file stats: %s", err.Error())
-		return
-	}
-
-	if fs.Mode()&mode != mode {
-		log.Printf("    Log file %s not user read/write: %s - changing permissions", itemName, fs.Mode().String())
-		newMod := fs.Mode() | mode
-		os.Chmod(itemName, newMod)
-	}
-}
-
-// function to check the permissions on the log files
-func checkLogFiles() {
-	// gather the names of the current nodes
-	nodes := getCurrNodeXnames()
-
-	log.Printf("Checking log file permissions")
-
-	// Check the write permissions on the dirs
-	// NOTE - we still don't know what is changing them, but this changes them back
-	checkItemPermissions("/var/log/conman", 0777)
-	checkItemPermissions("/var/log/conman.old", 0777)
-	checkItemPermissions("/var/log/console", 0777)
-
-	// check the write permissions of the log files this node is monitoring
-	for _, nn := range nodes {
-		filename := fmt.Sprintf("/var/log/conman/console.%s", nn)
-		checkItemPermissions(filename, 0666)
-	}
-}
-
 // function to continuously monitor for changes that require conman to restart
 func doMonitor() {
 	// NOTE: this is intended to be constantly running in its own thread
@@ -116,24 +84,22 @@ func doMonitor() {
 
 // function to check if the passwords have changed since conman was configured
 func checkIfRiverPasswordsChanged() bool {
+	// only hold the node lock while copying what was configured
+	currNodesMutex.Lock()
 	if previousPasswords == nil {
 		// this shouldn't happen due to the order of initialization, but just to be safe we skip this case.
+		currNodesMutex.Unlock()
 		return false
 	}
-
-	currNodesMutex.Lock()
-	defer currNodesMutex.Unlock()
-
-	var xnames []string = nil
-	allNodes := [2](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes}
-	for _, ar := range allNodes {
-		for _, nodeCi := range *ar {
-			xnames = append(xnames, nodeCi.BmcName)
-		}
+	configured := make(map[string]compcreds.CompCredentials)
+	for bmc, creds := range previousPasswords {
+		configured[bmc] = creds
 	}
+	currNodesMutex.Unlock()
+	xnames := getCurrCredBmcXnames()
 
-	// don't retry here so we don't block heartbeats with the mutex.  we can check again the next pass
-	currentPasswords := getPasswords(xnames)
+	// don't retry here, we can check again the next pass
+	currentPasswords := getPasswords(xnames, true)
 
 	for _, xname := range xnames {
 		currentCreds, ok := currentPasswords[xname]
@@ -141,7 +107,11 @@ func checkIfRiverPasswordsChanged() bool {
 			log.Printf("Missing credentials detected for %s while checking for credential changes", xname)
 			continue
 		}
-		previousCreds, _ := previousPasswords[xname]
+		previousCreds, ok := configured[xname]
+		if !ok {
+			// consoles waiting on creds are added by watchPendingCredentials
+			continue
+		}
 		if (currentCreds.Username != previousCreds.Username) || (currentCreds.Password != previousCreds.Password) {
 			log.Printf("Change detected in the river passwords.  Conman will be reconfigured.")
 			return true
@@ -152,12 +122,17 @@ func checkIfRiverPasswordsChanged() bool {
 
 // function to check if the console keys have changed since the last run of this function
 func checkIfMountainConsoleKeysChanged() bool {
-	// Checking for a mountain ssh key happens in the update config thread, need to use that mutex
-	currNodesMutex.Lock()
-	defer currNodesMutex.Unlock()
+	// if there are no mountain nodes configured the key does not matter
+	if !haveMountainConsoles() {
+		return false
+	}
 
-	// this returns true is the key has changes
-	return ensureMountainConsoleKeysPresent()
+	// this returns true if the key has changed
+	changed, err := ensureMountainConsoleKeysPresent()
+	if err != nil {
+		log.Printf("Unable to check the mountain ssh key: %s", err)
+	}
+	return changed
 }
 
 /*
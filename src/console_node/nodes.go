@@ -1,7 +1,7 @@
 //
 //  MIT License
 //
-//  (C) Copyright 2019-2025 Hewlett Packard Enterprise Development LP
+//  (C) Copyright 2019-2026 Hewlett Packard Enterprise Development LP
 //
 //  Permission is hereby granted, free of charge, to any person obtaining a
 //  copy of this software and associated documentation files (the "Software"),
@@ -45,6 +45,7 @@ type nodeConsoleInfo struct {
 	Class    string // river/mtn class
 	NID      int    // NID of the node
 	Role     string // role of the node
+	Tenant   string // tenant owning the node, empty for none
 }
 
 // Function to determine if a node is Mountain hardware
@@ -64,8 +65,8 @@ func (node nodeConsoleInfo) isParadise() bool {
 
 // Provide a function to convert struct to string
 func (node nodeConsoleInfo) String() string {
-	return fmt.Sprintf("NodeName:%s, BmcName:%s, BmcFqdn:%s, Class:%s, NID:%d, Role:%s",
-		node.NodeName, node.BmcName, node.BmcFqdn, node.Class, node.NID, node.Role)
+	return fmt.Sprintf("NodeName:%s, BmcName:%s, BmcFqdn:%s, Class:%s, NID:%d, Role:%s, Tenant:%s",
+		node.NodeName, node.BmcName, node.BmcFqdn, node.Class, node.NID, node.Role, node.Tenant)
 }
 
 // Globals for managing nodes being watched
@@ -116,6 +117,23 @@ func getCurrNodeXnames() []string {
 	return retVal
 }
 
+// function to safely get the bmc xnames of the consoles that use passwords
+func getCurrCredBmcXnames() []string {
+	currNodesMutex.Lock()
+	defer currNodesMutex.Unlock()
+
+	// River and Paradise consoles log in with the bmc credentials
+	var retVal []string
+	for _, nodeCi := range currentRvrNodes {
+		retVal = append(retVal, nodeCi.BmcName)
+	}
+	for _, nodeCi := range currentPdsNodes {
+		retVal = append(retVal, nodeCi.BmcName)
+	}
+
+	return retVal
+}
+
 // small helper function to insure correct number of nodes asked for
 func pinNumNodes(numAsk, numMax int) int {
 	// insure the input number ends in range [0,numMax]
@@ -160,7 +178,7 @@ func calcChangeInNodes() (deltaMtn, deltaRvr int) {
 	currNumMtn := len(currentMtnNodes)
 
 	// get the number of currently active nodes from the data service
-	numPods, errNumPods := getNumActiveNodePods()
+	numPods, errNumPods := dataService.getNumActiveNodePods()
 	if errNumPods != nil {
 		log.Print("Unable to find current number of active nodes, defaulting to 1")
 		numPods = 1
@@ -232,10 +250,12 @@ func doGetNewNodes() {
 	numAcqMtn := pinNumNodes(deltaMtn, maxAcquireMtn)
 
 	if numAcqRvr > 0 || numAcqMtn > 0 {
-		newNodes := acquireNewNodes(numAcqMtn, numAcqRvr, podLocData)
+		newNodes := dataService.acquireNewNodes(numAcqMtn, numAcqRvr, podLocData)
+		assignTenants(newNodes)
 		// process the new nodes
 		// NOTE: this should be the ONLY place where the maps of
-		//  current nodes is updated!!!
+		//  current nodes is updated!!! (syncInventory takes its place
+		//  when running standalone)
 		newRvr := 0
 		newMtn := 0
 		newPds := 0
@@ -0,0 +1,296 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the ownership and permission policy of the log files
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// Ownership and permission policy of the log directories and files
+// NOTE: the owner and group default to the user and group of the process, and
+// the logs stay readable by everyone until a group to share them with is set
+var logOwnerUID int = -1                                // owner of the log files
+var logGroupGID int = -1                                // group the log files are shared with
+var logDirMode os.FileMode = 0755                       // mode of the log directories
+var logFileMode os.FileMode = 0644                      // mode of the log files
+var logPolicyEnforce bool = true                        // fix what does not match the policy
+var logSharedDirMode os.FileMode = 0750 | os.ModeSetgid // with a group set, new files pick up the group of the directory
+var logSharedFileMode os.FileMode = 0640                // with a group set, readable by the sharing group
+
+// Globals holding the state of the policy
+var logPolicyMutex = &sync.Mutex{}
+var logPolicyConsoles map[string]bool = make(map[string]bool)     // [xname,true] consoles of this pod
+var logPolicyFindings map[string]string = make(map[string]string) // [path,finding] already reported
+var logPolicyWatcher *fsnotify.Watcher = nil
+
+// Directories the policy covers
+// NOTE: the console directories are shared with other pods, so only the files
+// of the consoles of this pod are checked in them
+func logPolicyRoots() []string {
+	return []string{consoleLogDir, logRotDir, "/var/log/console", conAggLogDir}
+}
+
+// Read a file mode given in octal
+func readSingleEnvVarMode(envVar string, outVar *os.FileMode) {
+	val := os.Getenv(envVar)
+	if val == "" {
+		return
+	}
+	m, err := strconv.ParseUint(val, 8, 32)
+	if err != nil || m > 07777 {
+		log.Printf("Invalid %s, expected an octal mode: %s", envVar, val)
+		return
+	}
+	mode := os.FileMode(m & 0777)
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	*outVar = mode
+	log.Printf("Found %s: %s", envVar, val)
+}
+
+// Read the policy from the environment and set up the process to follow it
+func initLogPolicy() {
+	logOwnerUID = os.Getuid()
+	logGroupGID = os.Getgid()
+	readSingleEnvVarInt("LOG_OWNER_UID", &logOwnerUID, 0, 1<<31-1)
+	if os.Getenv("LOG_GROUP_GID") != "" {
+		// sidecars in the group keep read access, everyone else loses it
+		readSingleEnvVarInt("LOG_GROUP_GID", &logGroupGID, 0, 1<<31-1)
+		logDirMode = logSharedDirMode
+		logFileMode = logSharedFileMode
+	}
+	readSingleEnvVarMode("LOG_DIR_MODE", &logDirMode)
+	readSingleEnvVarMode("LOG_FILE_MODE", &logFileMode)
+	if val := os.Getenv("LOG_POLICY_ENFORCE"); val != "" {
+		logPolicyEnforce = isTrue(val)
+	}
+	log.Printf("Log policy: uid:%d, gid:%d, dir mode:%s, file mode:%s, enforce:%v",
+		logOwnerUID, logGroupGID, logDirMode, logFileMode, logPolicyEnforce)
+
+	// conmand and logrotate inherit the umask, so new files start out close to the policy
+	syscall.Umask(int(^logDirMode.Perm() & 0777))
+}
+
+// Start watching the log directories for new files
+func startLogPolicyWatcher() {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Unable to watch the log directories: %s", err)
+		return
+	}
+	logPolicyMutex.Lock()
+	logPolicyWatcher = w
+	logPolicyMutex.Unlock()
+	go watchLogPolicy(w)
+}
+
+// Check the files of a console as the pod starts monitoring it
+func startLogPolicy(xname string) {
+	logPolicyMutex.Lock()
+	logPolicyConsoles[xname] = true
+	logPolicyMutex.Unlock()
+}
+
+// Stop checking the files of a console that is no longer monitored
+func removeLogPolicy(xname string) {
+	logPolicyMutex.Lock()
+	delete(logPolicyConsoles, xname)
+	logPolicyMutex.Unlock()
+}
+
+// Find the console a log file belongs to from names like console.xname.N
+func logFileConsole(path string) string {
+	base := filepath.Base(path)
+	if !strings.HasPrefix(base, "console.") {
+		return ""
+	}
+	xname := strings.TrimPrefix(base, "console.")
+	if pos := strings.Index(xname, "."); pos >= 0 {
+		xname = xname[:pos]
+	}
+	return xname
+}
+
+// Check if a file is covered by the policy of this pod
+func logPolicyCovers(path string) bool {
+	// everything in the local aggregation directory belongs to this pod
+	if strings.HasPrefix(path, conAggLogDir+"/") {
+		return true
+	}
+	logPolicyMutex.Lock()
+	defer logPolicyMutex.Unlock()
+	return logPolicyConsoles[logFileConsole(path)]
+}
+
+// Check a single file or directory against the policy
+func checkLogPolicy(path string) {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSymlink != 0 {
+		return
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	want := logFileMode
+	if fi.IsDir() {
+		want = logDirMode
+	}
+	have := fi.Mode() & (os.ModePerm | os.ModeSetgid)
+
+	var problems []string
+	if int(st.Uid) != logOwnerUID {
+		problems = append(problems, fmt.Sprintf("owner %d", st.Uid))
+	}
+	if int(st.Gid) != logGroupGID {
+		problems = append(problems, fmt.Sprintf("group %d", st.Gid))
+	}
+	if have != want {
+		problems = append(problems, fmt.Sprintf("mode %s", have))
+	}
+	if len(problems) == 0 {
+		logPolicyMutex.Lock()
+		delete(logPolicyFindings, path)
+		logPolicyMutex.Unlock()
+		return
+	}
+
+	// fix what is wrong if allowed to
+	finding := strings.Join(problems, ", ")
+	fixed := false
+	if logPolicyEnforce {
+		fixed = true
+		if int(st.Uid) != logOwnerUID || int(st.Gid) != logGroupGID {
+			if err := os.Lchown(path, logOwnerUID, logGroupGID); err != nil {
+				log.Printf("Unable to change the owner of %s: %s", path, err)
+				fixed = false
+			}
+		}
+		if have != want {
+			if err := os.Chmod(path, want); err != nil {
+				log.Printf("Unable to change the mode of %s: %s", path, err)
+				fixed = false
+			}
+		}
+	}
+
+	// only report each problem once
+	logPolicyMutex.Lock()
+	reported := logPolicyFindings[path] == finding
+	if fixed {
+		delete(logPolicyFindings, path)
+	} else {
+		logPolicyFindings[path] = finding
+	}
+	logPolicyMutex.Unlock()
+	if reported {
+		return
+	}
+
+	expected := fmt.Sprintf("owner %d, group %d, mode %s", logOwnerUID, logGroupGID, want)
+	details := map[string]string{"path": path, "found": finding, "expected": expected, "fixed": strconv.FormatBool(fixed)}
+	if fixed {
+		raiseEvent(logFileConsole(path), "log_permissions_fixed", eventInfo,
+			fmt.Sprintf("%s had %s, changed to %s", path, finding, expected), details)
+	} else {
+		raiseEvent(logFileConsole(path), "log_permissions", eventWarning,
+			fmt.Sprintf("%s has %s, expected %s", path, finding, expected), details)
+	}
+}
+
+// Check all the log directories and the files of this pod against the policy
+func enforceLogPolicy() {
+	log.Printf("Checking log file ownership and permissions")
+	logPolicyMutex.Lock()
+	w := logPolicyWatcher
+	logPolicyMutex.Unlock()
+
+	for _, root := range logPolicyRoots() {
+		// NOTE: never walk a relative path, that would be the working directory
+		if !filepath.IsAbs(root) {
+			log.Printf("Skipping the log policy of %q, it is not an absolute path", root)
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				checkLogPolicy(path)
+				if w != nil {
+					w.Add(path)
+				}
+			} else if logPolicyCovers(path) {
+				checkLogPolicy(path)
+			}
+			return nil
+		})
+	}
+}
+
+// Check new files as they are created rather than waiting for the next pass
+func watchLogPolicy(w *fsnotify.Watcher) {
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Chmod) == 0 {
+				continue
+			}
+			fi, err := os.Lstat(ev.Name)
+			if err != nil {
+				continue
+			}
+			if fi.IsDir() {
+				// new tenant directories need watching too
+				checkLogPolicy(ev.Name)
+				w.Add(ev.Name)
+			} else if logPolicyCovers(ev.Name) {
+				checkLogPolicy(ev.Name)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Error watching the log directories: %s", err)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// File contains the power control of the nodes through the power services
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Power actions that may be requested
+const (
+	powerOn     string = "on"
+	powerOff    string = "off"
+	powerReset  string = "reset"
+	powerStatus string = "status"
+)
+
+// PowerService - interface for interacting with the service that controls node power
+type PowerService interface {
+	PowerStatus(xname string) (string, error)
+	PowerAction(xname, action, reason string) error
+	Name() string
+}
+
+// NewPowerService creates the power service selected by the environment
+func NewPowerService() PowerService {
+	api := strings.ToLower(os.Getenv("POWER_API"))
+	if api == "" && simulationMode() {
+		api = "stub"
+	}
+	url := os.Getenv("POWER_API_URL")
+	switch api {
+	case "capmc":
+		if url == "" {
+			url = "http://cray-capmc/capmc/capmc/v1"
+		}
+		log.Printf("Using CAPMC for power control at %s", url)
+		return &CapmcManager{capmcAddrBase: url}
+	case "stub":
+		log.Printf("Using the local stub for power control")
+		return NewStubPowerService()
+	case "", "pcs":
+		if url == "" {
+			url = "http://cray-power-control/power-control/v1"
+		}
+		log.Printf("Using PCS for power control at %s", url)
+		return &PcsManager{pcsAddrBase: url}
+	}
+	log.Printf("Unknown POWER_API %s, power control is not available", api)
+	return nil
+}
+
+// PcsManager - struct for managing power control service interactions
+type PcsManager struct {
+	pcsAddrBase string
+}
+
+// Name of the power service
+func (pm PcsManager) Name() string {
+	return "pcs"
+}
+
+// PcsTransition represents a power transition request to PCS.
+type PcsTransition struct {
+	Operation    string        `json:"operation"`
+	TaskDeadline int           `json:"taskDeadlineMinutes,omitempty"`
+	Location     []PcsLocation `json:"location"`
+}
+
+// PcsLocation represents a single component of a PCS transition.
+type PcsLocation struct {
+	Xname string `json:"xname"`
+}
+
+// PcsPowerStatus represents the power status response from PCS.
+type PcsPowerStatus struct {
+	Status []struct {
+		Xname      string `json:"xname"`
+		PowerState string `json:"powerState"`
+		Error      string `json:"error"`
+	} `json:"status"`
+}
+
+// Get the power state of a node from PCS
+func (pm PcsManager) PowerStatus(xname string) (string, error) {
+	url := fmt.Sprintf("%s/power-status?xname=%s", pm.pcsAddrBase, xname)
+	rb, sc, err := getURL(url, nil)
+	if err != nil {
+		log.Printf("Error making GET to %s\n", url)
+		return "", err
+	}
+	if sc != http.StatusOK {
+		return "", fmt.Errorf("power status of %s failed, sc=%d", xname, sc)
+	}
+	var resp PcsPowerStatus
+	if err := json.Unmarshal(rb, &resp); err != nil {
+		log.Printf("Error unmarshalling return data: %s\n", err)
+		return "", err
+	}
+	for _, st := range resp.Status {
+		if st.Xname == xname {
+			if st.Error != "" {
+				return "", errors.New(st.Error)
+			}
+			return strings.ToLower(st.PowerState), nil
+		}
+	}
+	return "", fmt.Errorf("no power status returned for %s", xname)
+}
+
+// Request a power transition of a node from PCS
+func (pm PcsManager) PowerAction(xname, action, reason string) error {
+	ops := map[string]string{powerOn: "On", powerOff: "Force-Off", powerReset: "Hard-Restart"}
+	op, ok := ops[action]
+	if !ok {
+		return fmt.Errorf("unknown power action %s", action)
+	}
+	data, _ := json.Marshal(PcsTransition{Operation: op, Location: []PcsLocation{{Xname: xname}}})
+	url := fmt.Sprintf("%s/transitions", pm.pcsAddrBase)
+	_, sc, err := postURL(url, data, nil)
+	if err != nil {
+		log.Printf("Error making POST to %s\n", url)
+		return err
+	}
+	if sc != http.StatusOK && sc != http.StatusCreated && sc != http.StatusAccepted {
+		return fmt.Errorf("power %s of %s failed, sc=%d", action, xname, sc)
+	}
+	return nil
+}
+
+// CapmcManager - struct for managing CAPMC interactions
+type CapmcManager struct {
+	capmcAddrBase string
+}
+
+// Name of the power service
+func (cm CapmcManager) Name() string {
+	return "capmc"
+}
+
+// CapmcRequest represents a request to CAPMC.
+type CapmcRequest struct {
+	Xnames []string `json:"xnames"`
+	Reason string   `json:"reason,omitempty"`
+	Force  bool     `json:"force,omitempty"`
+}
+
+// CapmcResponse represents the common part of a CAPMC response.
+type CapmcResponse struct {
+	E      int      `json:"e"`
+	ErrMsg string   `json:"err_msg"`
+	On     []string `json:"on"`
+	Off    []string `json:"off"`
+}
+
+// Make a call to CAPMC and check the response
+func (cm CapmcManager) call(op string, req CapmcRequest) (*CapmcResponse, error) {
+	data, _ := json.Marshal(req)
+	url := fmt.Sprintf("%s/%s", cm.capmcAddrBase, op)
+	rb, sc, err := postURL(url, data, nil)
+	if err != nil {
+		log.Printf("Error making POST to %s\n", url)
+		return nil, err
+	}
+	var resp CapmcResponse
+	if rb != nil {
+		if err := json.Unmarshal(rb, &resp); err != nil {
+			log.Printf("Error unmarshalling return data: %s\n", err)
+			return nil, err
+		}
+	}
+	if sc != http.StatusOK || resp.E != 0 {
+		return nil, fmt.Errorf("capmc %s failed, sc=%d: %s", op, sc, resp.ErrMsg)
+	}
+	return &resp, nil
+}
+
+// Get the power state of a node from CAPMC
+func (cm CapmcManager) PowerStatus(xname string) (string, error) {
+	resp, err := cm.call("get_xname_status", CapmcRequest{Xnames: []string{xname}})
+	if err != nil {
+		return "", err
+	}
+	for _, x := range resp.On {
+		if x == xname {
+			return powerOn, nil
+		}
+	}
+	for _, x := range resp.Off {
+		if x == xname {
+			return powerOff, nil
+		}
+	}
+	return "undefined", nil
+}
+
+// Request a power transition of a node from CAPMC
+func (cm CapmcManager) PowerAction(xname, action, reason string) error {
+	ops := map[string]string{powerOn: "xname_on", powerOff: "xname_off", powerReset: "xname_reinit"}
+	op, ok := ops[action]
+	if !ok {
+		return fmt.Errorf("unknown power action %s", action)
+	}
+	_, err := cm.call(op, CapmcRequest{Xnames: []string{xname}, Reason: reason, Force: action != powerOn})
+	return err
+}
+
+// StubPowerManager - local stand in for a power service
+type StubPowerManager struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+// NewStubPowerService creates a stub power service with all nodes on
+func NewStubPowerService() *StubPowerManager {
+	return &StubPowerManager{states: make(map[string]string)}
+}
+
+// Name of the power service
+func (sm *StubPowerManager) Name() string {
+	return "stub"
+}
+
+// Get the power state of a node
+func (sm *StubPowerManager) PowerStatus(xname string) (string, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if st, ok := sm.states[xname]; ok {
+		return st, nil
+	}
+	return powerOn, nil
+}
+
+// Change the power state of a node
+func (sm *StubPowerManager) PowerAction(xname, action, reason string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	switch action {
+	case powerOn, powerReset:
+		sm.states[xname] = powerOn
+	case powerOff:
+		sm.states[xname] = powerOff
+	default:
+		return fmt.Errorf("unknown power action %s", action)
+	}
+	return nil
+}
+
+// Service used for power control, nil if there is none
+var powerService PowerService = nil
+
+// PowerRequest - a power action to take on a node
+type PowerRequest struct {
+	Action string `json:"action"`
+}
+
+// PowerResponse - the result of a power action
+type PowerResponse struct {
+	Xname  string `json:"xname"`
+	Action string `json:"action"`
+	State  string `json:"state,omitempty"`
+}
+
+// Put a marker with the console output so the power action shows with it
+func writeConsoleMarker(xname, text string) {
+	// NOTE: conmand owns the console log, the marker goes everywhere the tailed
+	//  output goes and next to the time index of the log
+	now := time.Now()
+	marker := "[console-node] " + text
+	writeConsoleToAggLog(xname, marker, now)
+	recordConsoleLine(xname, marker, now)
+	addConsoleMark(xname, marker, now)
+}
+
+// Handle a request to control the power of a node
+func doConsolePower(w http.ResponseWriter, r *http.Request, xname string) {
+	if powerService == nil {
+		sendJSONError(w, http.StatusServiceUnavailable, "Power control is not configured")
+		return
+	}
+
+	var req PowerRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Action = powerStatus
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+		if err != nil || json.Unmarshal(body, &req) != nil {
+			sendJSONError(w, http.StatusBadRequest, "Invalid power request")
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	resp := PowerResponse{Xname: xname, Action: req.Action}
+	switch req.Action {
+	case powerStatus:
+		state, err := powerService.PowerStatus(xname)
+		if err != nil {
+			sendJSONError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		resp.State = state
+	case powerOn, powerOff, powerReset:
+		user := requestUser(r)
+		reason := fmt.Sprintf("console-node power %s requested by %s", req.Action, user)
+		writeConsoleMarker(xname, fmt.Sprintf("power %s requested by %s through %s", req.Action, user, powerService.Name()))
+		auditPowerAction(r, xname, req.Action, powerService.Name(), nil)
+		raiseEvent(xname, "power_"+req.Action, eventInfo, reason,
+			map[string]string{"user": user, "remote": r.RemoteAddr, "service": powerService.Name()})
+		if err := powerService.PowerAction(xname, req.Action, reason); err != nil {
+			writeConsoleMarker(xname, fmt.Sprintf("power %s failed: %s", req.Action, err))
+			auditPowerAction(r, xname, req.Action, powerService.Name(), err)
+			sendJSONError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeConsoleMarker(xname, fmt.Sprintf("power %s accepted by %s", req.Action, powerService.Name()))
+	default:
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown power action: %q", req.Action))
+		return
+	}
+	SendResponseJSON(w, http.StatusOK, resp)
+}
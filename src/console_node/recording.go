@@ -0,0 +1,585 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the code to record console sessions in the asciinema
+// v2 (asciicast) format and export them for replay
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Location of the console recordings
+// NOTE: the rotated recordings are kept on the shared pvc next to the rotated logs
+const recordingDir string = "/var/log/conman/recordings"
+const recordingOldDir string = "/var/log/conman.old/recordings"
+
+// Globals for recording parameters
+var recordingEnabled bool = true
+var recordingMaxMB int = 5            // size of a recording file before it is rotated
+var recordingFlushSecs int = 2        // how often buffered recording data is written to disk
+var recordingSubscriberBuf int = 1000 // number of events a live subscriber may fall behind
+
+// Header line of an asciicast v2 file
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// A single output event captured from a console
+type recordingEvent struct {
+	when time.Time
+	data string
+}
+
+// State of the recording of a single console
+type consoleRecorder struct {
+	mu          sync.Mutex
+	xname       string
+	file        *os.File
+	writer      *bufio.Writer
+	start       time.Time
+	size        int64
+	index       *timeIndex
+	subscribers map[chan recordingEvent]bool
+}
+
+// Globals to hold the recordings of all monitored consoles
+var recordersMutex = &sync.Mutex{}
+var consoleRecorders map[string]*consoleRecorder = make(map[string]*consoleRecorder) // [xname,*consoleRecorder]
+
+// Read the recording configuration from the environment and start the flush thread
+func initRecordings() {
+	if val := os.Getenv("CONSOLE_RECORDING_ENABLE"); val != "" {
+		log.Printf("Found CONSOLE_RECORDING_ENABLE: %s", val)
+		recordingEnabled = isTrue(val)
+	}
+	readSingleEnvVarInt("CONSOLE_RECORDING_MAX_MB", &recordingMaxMB, 1, 1024)
+	readSingleEnvVarInt("CONSOLE_RECORDING_FLUSH_SEC", &recordingFlushSecs, 1, 60)
+	log.Printf("Console recording enabled: %v, max size MB: %d", recordingEnabled, recordingMaxMB)
+
+	if !recordingEnabled {
+		return
+	}
+	ensureDirPresent(recordingDir, logDirMode)
+	ensureDirPresent(recordingOldDir, logDirMode)
+	go flushRecordings()
+}
+
+// Name of the active recording file for a console
+func recordingFile(xname string) string {
+	return filepath.Join(tenantDir(recordingDir, xname), fmt.Sprintf("console.%s.cast", xname))
+}
+
+// Name of a rotated recording file for a console
+func rotatedRecordingFile(xname string, num int) string {
+	return filepath.Join(tenantDir(recordingOldDir, xname), fmt.Sprintf("console.%s.cast.%d", xname, num))
+}
+
+// Name of the time index of the active recording file for a console
+func recordingIndexFile(xname string) string {
+	return recordingFile(xname) + ".tidx"
+}
+
+// Name of the time index of a rotated recording file for a console
+func rotatedRecordingIndexFile(xname string, num int) string {
+	return filepath.Join(tenantDir(recordingOldDir, xname), fmt.Sprintf("console.%s.cast.tidx.%d", xname, num))
+}
+
+// Start recording the output of a console
+func startRecording(xname string) {
+	if !recordingEnabled {
+		return
+	}
+	recordersMutex.Lock()
+	defer recordersMutex.Unlock()
+	if _, ok := consoleRecorders[xname]; ok {
+		return
+	}
+	cr := &consoleRecorder{xname: xname, subscribers: make(map[chan recordingEvent]bool)}
+	if err := cr.open(); err != nil {
+		log.Printf("Unable to start recording for %s: %s", xname, err)
+	}
+	consoleRecorders[xname] = cr
+}
+
+// Stop recording the output of a console
+func stopRecording(xname string) {
+	recordersMutex.Lock()
+	cr, ok := consoleRecorders[xname]
+	delete(consoleRecorders, xname)
+	recordersMutex.Unlock()
+
+	if ok {
+		cr.mu.Lock()
+		defer cr.mu.Unlock()
+		cr.close()
+		// let any live viewers know there is nothing more coming
+		for ch := range cr.subscribers {
+			close(ch)
+		}
+		cr.subscribers = nil
+	}
+}
+
+// Look up the recorder of a console
+func getRecorder(xname string) *consoleRecorder {
+	recordersMutex.Lock()
+	defer recordersMutex.Unlock()
+	return consoleRecorders[xname]
+}
+
+// Record a line of console output captured at the given time
+func recordConsoleLine(xname, text string, when time.Time) {
+	cr := getRecorder(xname)
+	if cr == nil {
+		return
+	}
+
+	// conman timestamps are redundant with the capture time
+	if !isConmanMessage(text) {
+		_, text = splitConmanTimestamp(text)
+	}
+	cr.record(recordingEvent{when: when, data: desanitizeConsoleText(text) + "\r\n"})
+}
+
+// Open the recording file - appending if there is already one present
+func (cr *consoleRecorder) open() error {
+	fn := recordingFile(cr.xname)
+	f, err := os.OpenFile(fn, os.O_APPEND|os.O_WRONLY|os.O_CREATE, logFileMode)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	cr.file = f
+	cr.writer = bufio.NewWriter(f)
+	cr.size = fi.Size()
+	cr.index = openTimeIndex(recordingIndexFile(cr.xname))
+
+	if cr.size == 0 {
+		// new file - write the header
+		// NOTE: the header only holds whole seconds, start the event times from
+		//  the same second readers will rebuild from it
+		cr.start = time.Unix(time.Now().Unix(), 0)
+		hdr, _ := json.Marshal(asciicastHeader{
+			Version:   2,
+			Width:     screenCols,
+			Height:    screenRows,
+			Timestamp: cr.start.Unix(),
+			Title:     cr.xname,
+			Env:       map[string]string{"TERM": "xterm"},
+		})
+		n, _ := cr.writer.Write(append(hdr, '\n'))
+		cr.size += int64(n)
+	} else if hdr, err := readAsciicastHeader(fn); err == nil {
+		// continuing an existing file - event times are relative to its header
+		cr.start = time.Unix(hdr.Timestamp, 0)
+	} else {
+		log.Printf("Unable to read recording header of %s, starting a new file: %s", fn, err)
+		cr.close()
+		// set the damaged file aside so it can still be looked at
+		if err := os.Rename(fn, fn+".damaged"); err != nil {
+			return err
+		}
+		return cr.open()
+	}
+	return nil
+}
+
+// Flush and close the recording file
+func (cr *consoleRecorder) close() {
+	if cr.file == nil {
+		return
+	}
+	if err := cr.writer.Flush(); err != nil {
+		log.Printf("Error flushing recording for %s: %s", cr.xname, err)
+	}
+	cr.file.Close()
+	cr.file = nil
+	cr.writer = nil
+	cr.index.close()
+}
+
+// Move the current recording into the backup directory and start a new one
+func (cr *consoleRecorder) rotate() error {
+	cr.close()
+
+	// shift the older copies back one - the oldest falls off the end
+	shiftRotatedFiles(recordingFile(cr.xname),
+		func(num int) string { return rotatedRecordingFile(cr.xname, num) }, logRotConNumRotate)
+	shiftRotatedFiles(recordingIndexFile(cr.xname),
+		func(num int) string { return rotatedRecordingIndexFile(cr.xname, num) }, logRotConNumRotate)
+	return cr.open()
+}
+
+// Write an event to the recording and hand it to any live viewers
+func (cr *consoleRecorder) record(ev recordingEvent) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	if cr.writer != nil {
+		line, err := json.Marshal([]interface{}{asciicastTime(ev.when, cr.start), "o", ev.data})
+		if err == nil {
+			cr.index.add(ev.when, cr.size)
+			n, err := cr.writer.Write(append(line, '\n'))
+			if err != nil {
+				log.Printf("Error writing recording for %s: %s", cr.xname, err)
+			}
+			cr.size += int64(n)
+		}
+		if cr.size > int64(recordingMaxMB)*1024*1024 {
+			if err := cr.rotate(); err != nil {
+				log.Printf("Unable to restart recording for %s: %s", cr.xname, err)
+			}
+		}
+	}
+
+	for ch := range cr.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// the viewer is not keeping up - drop rather than stall the tail
+		}
+	}
+}
+
+// Register for live events from the console
+func (cr *consoleRecorder) subscribe() chan recordingEvent {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	ch := make(chan recordingEvent, recordingSubscriberBuf)
+	if cr.subscribers == nil {
+		// recorder has already been stopped
+		close(ch)
+		return ch
+	}
+	cr.subscribers[ch] = true
+	return ch
+}
+
+// Stop receiving live events from the console
+func (cr *consoleRecorder) unsubscribe(ch chan recordingEvent) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if _, ok := cr.subscribers[ch]; ok {
+		delete(cr.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Periodically push buffered recording data out to disk
+func flushRecordings() {
+	for {
+		time.Sleep(time.Duration(recordingFlushSecs) * time.Second)
+
+		recordersMutex.Lock()
+		var recs []*consoleRecorder
+		for _, cr := range consoleRecorders {
+			recs = append(recs, cr)
+		}
+		recordersMutex.Unlock()
+
+		for _, cr := range recs {
+			cr.mu.Lock()
+			if cr.writer != nil {
+				if err := cr.writer.Flush(); err != nil {
+					log.Printf("Error flushing recording for %s: %s", cr.xname, err)
+				}
+			}
+			cr.mu.Unlock()
+		}
+	}
+}
+
+// Seconds between the start of the recording and the event
+func asciicastTime(when, start time.Time) float64 {
+	return float64(when.Sub(start).Microseconds()) / 1e6
+}
+
+// Read the header line of a recording file
+func readAsciicastHeader(fn string) (asciicastHeader, error) {
+	var hdr asciicastHeader
+	f, err := os.Open(fn)
+	if err != nil {
+		return hdr, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return hdr, err
+	}
+	err = json.Unmarshal([]byte(line), &hdr)
+	return hdr, err
+}
+
+// Parse a single event line from a recording file
+func parseAsciicastEvent(line []byte, start time.Time) (recordingEvent, bool) {
+	var ev []interface{}
+	if err := json.Unmarshal(line, &ev); err != nil || len(ev) != 3 {
+		return recordingEvent{}, false
+	}
+	secs, ok1 := ev[0].(float64)
+	data, ok2 := ev[2].(string)
+	if !ok1 || !ok2 {
+		return recordingEvent{}, false
+	}
+	return recordingEvent{when: start.Add(time.Duration(secs*1e6) * time.Microsecond), data: data}, true
+}
+
+// All the recording files of a console from oldest to newest
+func recordingFiles(xname string) []indexedLogFile {
+	var files []indexedLogFile
+	for i := logRotConNumRotate; i >= 1; i-- {
+		fn := rotatedRecordingFile(xname, i)
+		if _, err := os.Stat(fn); err == nil {
+			files = append(files, indexedLogFile{logFile: fn, indexFile: rotatedRecordingIndexFile(xname, i)})
+		}
+	}
+	if _, err := os.Stat(recordingFile(xname)); err == nil {
+		files = append(files, indexedLogFile{logFile: recordingFile(xname), indexFile: recordingIndexFile(xname)})
+	}
+	return files
+}
+
+// Read the recorded events of a console inside a time range
+func readRecordingEvents(xname string, since, until time.Time, handler func(ev recordingEvent) bool) error {
+	// make sure everything captured so far is on disk
+	if cr := getRecorder(xname); cr != nil {
+		cr.mu.Lock()
+		if cr.writer != nil {
+			cr.writer.Flush()
+		}
+		cr.mu.Unlock()
+	}
+
+	for _, rf := range recordingFiles(xname) {
+		if !readRecordingFile(rf, since, until, handler) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Read the events of a single recording file - returns false when done reading
+func readRecordingFile(rf indexedLogFile, since, until time.Time, handler func(ev recordingEvent) bool) bool {
+	f, err := os.Open(rf.logFile)
+	if err != nil {
+		log.Printf("Unable to open recording %s: %s", rf.logFile, err)
+		return true
+	}
+	defer f.Close()
+
+	// the header holds the time the event times are relative to
+	rd := bufio.NewReaderSize(f, 64*1024)
+	line, err := rd.ReadBytes('\n')
+	if err != nil {
+		log.Printf("Unable to read recording header of %s: %s", rf.logFile, err)
+		return true
+	}
+	var hdr asciicastHeader
+	if err := json.Unmarshal(line, &hdr); err != nil {
+		log.Printf("Invalid recording header in %s: %s", rf.logFile, err)
+		return true
+	}
+	start := time.Unix(hdr.Timestamp, 0)
+
+	// use the time index to skip straight to the requested time
+	if !since.IsZero() {
+		entries, err := readTimeIndex(rf.indexFile)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Unable to read time index %s: %s", rf.indexFile, err)
+		}
+		if offset := indexStartOffset(entries, since); offset > 0 {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				log.Printf("Unable to seek in %s: %s", rf.logFile, err)
+				return true
+			}
+			rd.Reset(f)
+		}
+	}
+
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(line) > 0 {
+			if ev, ok := parseAsciicastEvent(line, start); ok {
+				if !until.IsZero() && ev.when.After(until) {
+					return false
+				}
+				if (since.IsZero() || !ev.when.Before(since)) && !handler(ev) {
+					return false
+				}
+			}
+		}
+		if err != nil {
+			return true
+		}
+	}
+}
+
+// Writes an asciicast stream starting with the header at the first event
+type asciicastWriter struct {
+	w       http.ResponseWriter
+	xname   string
+	start   time.Time
+	started bool
+	err     error
+}
+
+// Write the header if it has not been written yet
+func (aw *asciicastWriter) writeHeader(start time.Time) {
+	if aw.started {
+		return
+	}
+	aw.started = true
+	aw.start = start
+	hdr, _ := json.Marshal(asciicastHeader{
+		Version:   2,
+		Width:     screenCols,
+		Height:    screenRows,
+		Timestamp: start.Unix(),
+		Title:     aw.xname,
+		Env:       map[string]string{"TERM": "xterm"},
+	})
+	_, aw.err = aw.w.Write(append(hdr, '\n'))
+}
+
+// Write a single event relative to the start of the stream
+func (aw *asciicastWriter) writeEvent(ev recordingEvent) bool {
+	aw.writeHeader(ev.when)
+	if aw.err != nil {
+		return false
+	}
+	line, _ := json.Marshal([]interface{}{asciicastTime(ev.when, aw.start), "o", ev.data})
+	_, aw.err = aw.w.Write(append(line, '\n'))
+	return aw.err == nil
+}
+
+// Handle the request for the recording of a console
+func doConsoleRecording(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+	if !recordingEnabled {
+		sendJSONError(w, http.StatusNotFound, "Console recording is not enabled")
+		return
+	}
+
+	// pull out the time range of the recording
+	q := r.URL.Query()
+	since, err := parseTimeParam(q.Get("since"))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since: %s", err))
+		return
+	}
+	until, err := parseTimeParam(q.Get("until"))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid until: %s", err))
+		return
+	}
+	follow := isTrue(q.Get("follow"))
+
+	// register for live events before reading the history so nothing is missed
+	var live chan recordingEvent
+	cr := getRecorder(xname)
+	if follow && cr != nil {
+		live = cr.subscribe()
+		defer cr.unsubscribe(live)
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	if !follow {
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=\"console.%s.cast\"", xname))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	aw := &asciicastWriter{w: w, xname: xname}
+	if !since.IsZero() {
+		// the replay starts at the requested time even if it was quiet then
+		aw.writeHeader(since)
+	}
+	var lastEvent time.Time
+	readRecordingEvents(xname, since, until, func(ev recordingEvent) bool {
+		lastEvent = ev.when
+		return aw.writeEvent(ev)
+	})
+
+	if live == nil {
+		aw.writeHeader(time.Now())
+		return
+	}
+
+	// stream new output as it arrives until the client goes away
+	aw.writeHeader(time.Now())
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-live:
+			if !ok {
+				// console is no longer monitored here
+				return
+			}
+			if !ev.when.After(lastEvent) {
+				// already sent from the history
+				continue
+			}
+			if !until.IsZero() && ev.when.After(until) {
+				return
+			}
+			if !aw.writeEvent(ev) {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,851 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains a minimal VT100/xterm screen emulator that is kept for
+// each console so the current contents of the screen can be viewed without
+// attaching to the console.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Size of the emulated screens - may be overridden through env vars
+var screenRows int = 25
+var screenCols int = 80
+
+// Number of bytes from the end of an existing log file used to seed a new screen
+const screenSeedBytes int64 = 32 * 1024
+
+// Parser states for the escape sequence handling
+const (
+	vtStateGround = iota
+	vtStateEscape
+	vtStateCSI
+	vtStateOSC
+	vtStateOSCEsc
+	vtStateCharset
+)
+
+// Display attributes of a single character cell
+type vtAttr struct {
+	fg        int // -1 is the default color, otherwise 0-255 palette index
+	bg        int // -1 is the default color, otherwise 0-255 palette index
+	bold      bool
+	underline bool
+	blink     bool
+	reverse   bool
+}
+
+// The default attributes for a cell
+var vtDefaultAttr = vtAttr{fg: -1, bg: -1}
+
+// A single character cell on the screen
+type vtCell struct {
+	ch   rune
+	attr vtAttr
+}
+
+// State of a single emulated screen
+type vtScreen struct {
+	mu           sync.Mutex
+	rows         int
+	cols         int
+	cells        [][]vtCell
+	curRow       int
+	curCol       int
+	savedRow     int
+	savedCol     int
+	attr         vtAttr
+	scrollTop    int
+	scrollBottom int
+	wrapPending  bool
+	state        int
+	csiParams    string
+	csiPrivate   byte
+	lastUpdate   time.Time
+}
+
+// ScreenSnapshot - the current contents of a console screen
+type ScreenSnapshot struct {
+	Xname      string `json:"xname"`
+	Rows       int    `json:"rows"`
+	Cols       int    `json:"cols"`
+	CursorRow  int    `json:"cursor_row"`
+	CursorCol  int    `json:"cursor_col"`
+	LastUpdate string `json:"last_update"`
+	Text       string `json:"text"`
+	HTML       string `json:"html"`
+}
+
+// Globals to hold the screens of all monitored consoles
+var screensMutex = &sync.Mutex{}
+var consoleScreens map[string]*vtScreen = make(map[string]*vtScreen) // [xname,*vtScreen]
+
+// Create a new blank screen of the given size
+func newVtScreen(rows, cols int) *vtScreen {
+	s := &vtScreen{rows: rows, cols: cols}
+	s.reset()
+	return s
+}
+
+// Put the screen back into the power on state
+func (s *vtScreen) reset() {
+	s.cells = make([][]vtCell, s.rows)
+	for i := range s.cells {
+		s.cells[i] = s.blankLine()
+	}
+	s.curRow = 0
+	s.curCol = 0
+	s.savedRow = 0
+	s.savedCol = 0
+	s.attr = vtDefaultAttr
+	s.scrollTop = 0
+	s.scrollBottom = s.rows - 1
+	s.wrapPending = false
+	s.state = vtStateGround
+}
+
+// Create a line of blank cells
+func (s *vtScreen) blankLine() []vtCell {
+	line := make([]vtCell, s.cols)
+	for i := range line {
+		line[i] = vtCell{ch: ' ', attr: vtDefaultAttr}
+	}
+	return line
+}
+
+// Feed a chunk of console output through the emulator
+func (s *vtScreen) write(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range data {
+		s.processRune(r)
+	}
+	s.lastUpdate = time.Now()
+}
+
+// Run a single character through the parser state machine
+func (s *vtScreen) processRune(r rune) {
+	switch s.state {
+	case vtStateEscape:
+		s.processEscape(r)
+		return
+	case vtStateCSI:
+		s.processCSI(r)
+		return
+	case vtStateOSC:
+		// operating system commands end with BEL or ST (ESC \)
+		if r == 0x07 {
+			s.state = vtStateGround
+		} else if r == 0x1b {
+			s.state = vtStateOSCEsc
+		}
+		return
+	case vtStateOSCEsc:
+		s.state = vtStateGround
+		return
+	case vtStateCharset:
+		// character set designations are ignored
+		s.state = vtStateGround
+		return
+	}
+
+	// ground state
+	switch {
+	case r == 0x1b:
+		s.state = vtStateEscape
+	case r == '\r':
+		s.curCol = 0
+		s.wrapPending = false
+	case r == '\n' || r == 0x0b || r == 0x0c:
+		s.lineFeed()
+	case r == '\b':
+		if s.curCol > 0 {
+			s.curCol--
+		}
+		s.wrapPending = false
+	case r == '\t':
+		s.curCol = (s.curCol/8 + 1) * 8
+		if s.curCol >= s.cols {
+			s.curCol = s.cols - 1
+		}
+	case r < 0x20 || r == 0x7f:
+		// remaining control characters are not displayed
+	default:
+		s.putChar(r)
+	}
+}
+
+// Handle the character following an escape
+func (s *vtScreen) processEscape(r rune) {
+	s.state = vtStateGround
+	switch r {
+	case '[':
+		s.state = vtStateCSI
+		s.csiParams = ""
+		s.csiPrivate = 0
+	case ']':
+		s.state = vtStateOSC
+	case '(', ')', '*', '+':
+		s.state = vtStateCharset
+	case '7':
+		s.saveCursor()
+	case '8':
+		s.restoreCursor()
+	case 'c':
+		s.reset()
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.curCol = 0
+		s.lineFeed()
+	case 'M':
+		s.reverseIndex()
+	}
+}
+
+// Collect the parameters of a control sequence and execute it when complete
+func (s *vtScreen) processCSI(r rune) {
+	switch {
+	case r >= '0' && r <= '9' || r == ';':
+		s.csiParams += string(r)
+	case r == '?' || r == '>' || r == '=' || r == '<':
+		s.csiPrivate = byte(r)
+	case r >= 0x20 && r <= 0x2f:
+		// intermediate bytes are ignored
+	case r >= 0x40 && r <= 0x7e:
+		s.state = vtStateGround
+		s.executeCSI(r, s.parseParams())
+	default:
+		// invalid sequence - abandon it
+		s.state = vtStateGround
+	}
+}
+
+// Split the collected control sequence parameters into integers
+func (s *vtScreen) parseParams() []int {
+	if s.csiParams == "" {
+		return nil
+	}
+	var params []int
+	for _, p := range strings.Split(s.csiParams, ";") {
+		v := 0
+		for _, c := range p {
+			v = v*10 + int(c-'0')
+			if v > 10000 {
+				v = 10000
+			}
+		}
+		params = append(params, v)
+	}
+	return params
+}
+
+// Get a parameter with a default when missing or zero
+func csiParam(params []int, idx, def int) int {
+	if idx < len(params) && params[idx] != 0 {
+		return params[idx]
+	}
+	return def
+}
+
+// Execute a complete control sequence
+func (s *vtScreen) executeCSI(final rune, params []int) {
+	s.wrapPending = false
+	switch final {
+	case 'A':
+		s.moveCursor(s.curRow-csiParam(params, 0, 1), s.curCol)
+	case 'B', 'e':
+		s.moveCursor(s.curRow+csiParam(params, 0, 1), s.curCol)
+	case 'C', 'a':
+		s.moveCursor(s.curRow, s.curCol+csiParam(params, 0, 1))
+	case 'D':
+		s.moveCursor(s.curRow, s.curCol-csiParam(params, 0, 1))
+	case 'E':
+		s.moveCursor(s.curRow+csiParam(params, 0, 1), 0)
+	case 'F':
+		s.moveCursor(s.curRow-csiParam(params, 0, 1), 0)
+	case 'G', '`':
+		s.moveCursor(s.curRow, csiParam(params, 0, 1)-1)
+	case 'd':
+		s.moveCursor(csiParam(params, 0, 1)-1, s.curCol)
+	case 'H', 'f':
+		s.moveCursor(csiParam(params, 0, 1)-1, csiParam(params, 1, 1)-1)
+	case 'J':
+		s.eraseDisplay(csiParam(params, 0, 0))
+	case 'K':
+		s.eraseLine(csiParam(params, 0, 0))
+	case 'L':
+		s.insertLines(csiParam(params, 0, 1))
+	case 'M':
+		s.deleteLines(csiParam(params, 0, 1))
+	case '@':
+		s.insertChars(csiParam(params, 0, 1))
+	case 'P':
+		s.deleteChars(csiParam(params, 0, 1))
+	case 'X':
+		s.eraseChars(csiParam(params, 0, 1))
+	case 'S':
+		s.scrollUp(csiParam(params, 0, 1))
+	case 'T':
+		s.scrollDown(csiParam(params, 0, 1))
+	case 'm':
+		if s.csiPrivate == 0 {
+			s.setGraphics(params)
+		}
+	case 'r':
+		if s.csiPrivate == 0 {
+			s.setScrollRegion(csiParam(params, 0, 1)-1, csiParam(params, 1, s.rows)-1)
+		}
+	case 's':
+		s.saveCursor()
+	case 'u':
+		s.restoreCursor()
+	case 'h', 'l':
+		// switching to or from the alternate screen starts with a clean display
+		if s.csiPrivate == '?' {
+			for _, p := range params {
+				if p == 47 || p == 1047 || p == 1049 {
+					s.eraseDisplay(2)
+				}
+			}
+		}
+	}
+}
+
+// Move the cursor keeping it on the screen
+func (s *vtScreen) moveCursor(row, col int) {
+	if row < 0 {
+		row = 0
+	} else if row >= s.rows {
+		row = s.rows - 1
+	}
+	if col < 0 {
+		col = 0
+	} else if col >= s.cols {
+		col = s.cols - 1
+	}
+	s.curRow = row
+	s.curCol = col
+	s.wrapPending = false
+}
+
+// Write a displayable character at the cursor
+func (s *vtScreen) putChar(r rune) {
+	if s.wrapPending {
+		s.curCol = 0
+		s.lineFeed()
+	}
+	s.cells[s.curRow][s.curCol] = vtCell{ch: r, attr: s.attr}
+	if s.curCol == s.cols-1 {
+		s.wrapPending = true
+	} else {
+		s.curCol++
+	}
+}
+
+// Move down a line, scrolling if at the bottom of the scroll region
+func (s *vtScreen) lineFeed() {
+	s.wrapPending = false
+	if s.curRow == s.scrollBottom {
+		s.scrollUp(1)
+	} else if s.curRow < s.rows-1 {
+		s.curRow++
+	}
+}
+
+// Move up a line, scrolling if at the top of the scroll region
+func (s *vtScreen) reverseIndex() {
+	s.wrapPending = false
+	if s.curRow == s.scrollTop {
+		s.scrollDown(1)
+	} else if s.curRow > 0 {
+		s.curRow--
+	}
+}
+
+// Scroll the scroll region up by n lines
+func (s *vtScreen) scrollUp(n int) {
+	for i := 0; i < n; i++ {
+		copy(s.cells[s.scrollTop:s.scrollBottom], s.cells[s.scrollTop+1:s.scrollBottom+1])
+		s.cells[s.scrollBottom] = s.blankLine()
+	}
+}
+
+// Scroll the scroll region down by n lines
+func (s *vtScreen) scrollDown(n int) {
+	for i := 0; i < n; i++ {
+		copy(s.cells[s.scrollTop+1:s.scrollBottom+1], s.cells[s.scrollTop:s.scrollBottom])
+		s.cells[s.scrollTop] = s.blankLine()
+	}
+}
+
+// Insert blank lines at the cursor inside the scroll region
+func (s *vtScreen) insertLines(n int) {
+	if s.curRow < s.scrollTop || s.curRow > s.scrollBottom {
+		return
+	}
+	saveTop := s.scrollTop
+	s.scrollTop = s.curRow
+	s.scrollDown(n)
+	s.scrollTop = saveTop
+}
+
+// Delete lines at the cursor inside the scroll region
+func (s *vtScreen) deleteLines(n int) {
+	if s.curRow < s.scrollTop || s.curRow > s.scrollBottom {
+		return
+	}
+	saveTop := s.scrollTop
+	s.scrollTop = s.curRow
+	s.scrollUp(n)
+	s.scrollTop = saveTop
+}
+
+// Insert blank characters at the cursor
+func (s *vtScreen) insertChars(n int) {
+	line := s.cells[s.curRow]
+	if n > s.cols-s.curCol {
+		n = s.cols - s.curCol
+	}
+	copy(line[s.curCol+n:], line[s.curCol:])
+	for i := s.curCol; i < s.curCol+n; i++ {
+		line[i] = vtCell{ch: ' ', attr: vtDefaultAttr}
+	}
+}
+
+// Delete characters at the cursor
+func (s *vtScreen) deleteChars(n int) {
+	line := s.cells[s.curRow]
+	if n > s.cols-s.curCol {
+		n = s.cols - s.curCol
+	}
+	copy(line[s.curCol:], line[s.curCol+n:])
+	for i := s.cols - n; i < s.cols; i++ {
+		line[i] = vtCell{ch: ' ', attr: vtDefaultAttr}
+	}
+}
+
+// Blank out characters starting at the cursor
+func (s *vtScreen) eraseChars(n int) {
+	s.clearCells(s.curRow, s.curCol, s.curCol+n)
+}
+
+// Blank out a range of cells on a row
+func (s *vtScreen) clearCells(row, from, to int) {
+	if to > s.cols {
+		to = s.cols
+	}
+	for i := from; i < to; i++ {
+		s.cells[row][i] = vtCell{ch: ' ', attr: vtDefaultAttr}
+	}
+}
+
+// Handle the erase in display sequence
+func (s *vtScreen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		// cursor to end of screen
+		s.clearCells(s.curRow, s.curCol, s.cols)
+		for i := s.curRow + 1; i < s.rows; i++ {
+			s.cells[i] = s.blankLine()
+		}
+	case 1:
+		// start of screen to cursor
+		for i := 0; i < s.curRow; i++ {
+			s.cells[i] = s.blankLine()
+		}
+		s.clearCells(s.curRow, 0, s.curCol+1)
+	default:
+		// entire screen
+		for i := 0; i < s.rows; i++ {
+			s.cells[i] = s.blankLine()
+		}
+	}
+}
+
+// Handle the erase in line sequence
+func (s *vtScreen) eraseLine(mode int) {
+	switch mode {
+	case 0:
+		s.clearCells(s.curRow, s.curCol, s.cols)
+	case 1:
+		s.clearCells(s.curRow, 0, s.curCol+1)
+	default:
+		s.clearCells(s.curRow, 0, s.cols)
+	}
+}
+
+// Set the top and bottom margins of the scroll region
+func (s *vtScreen) setScrollRegion(top, bottom int) {
+	if top < 0 || bottom >= s.rows || top >= bottom {
+		top = 0
+		bottom = s.rows - 1
+	}
+	s.scrollTop = top
+	s.scrollBottom = bottom
+	s.moveCursor(0, 0)
+}
+
+// Save the cursor position
+func (s *vtScreen) saveCursor() {
+	s.savedRow = s.curRow
+	s.savedCol = s.curCol
+}
+
+// Restore the saved cursor position
+func (s *vtScreen) restoreCursor() {
+	s.moveCursor(s.savedRow, s.savedCol)
+}
+
+// Handle the select graphic rendition sequence
+func (s *vtScreen) setGraphics(params []int) {
+	if len(params) == 0 {
+		s.attr = vtDefaultAttr
+		return
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			s.attr = vtDefaultAttr
+		case p == 1:
+			s.attr.bold = true
+		case p == 4:
+			s.attr.underline = true
+		case p == 5:
+			s.attr.blink = true
+		case p == 7:
+			s.attr.reverse = true
+		case p == 22:
+			s.attr.bold = false
+		case p == 24:
+			s.attr.underline = false
+		case p == 25:
+			s.attr.blink = false
+		case p == 27:
+			s.attr.reverse = false
+		case p >= 30 && p <= 37:
+			s.attr.fg = p - 30
+		case p == 39:
+			s.attr.fg = -1
+		case p >= 40 && p <= 47:
+			s.attr.bg = p - 40
+		case p == 49:
+			s.attr.bg = -1
+		case p >= 90 && p <= 97:
+			s.attr.fg = p - 90 + 8
+		case p >= 100 && p <= 107:
+			s.attr.bg = p - 100 + 8
+		case p == 38 || p == 48:
+			// extended colors - 5;n is a palette index, 2;r;g;b is not supported
+			if i+2 < len(params) && params[i+1] == 5 {
+				if p == 38 {
+					s.attr.fg = params[i+2] & 0xff
+				} else {
+					s.attr.bg = params[i+2] & 0xff
+				}
+				i += 2
+			} else if i+4 < len(params) && params[i+1] == 2 {
+				i += 4
+			}
+		}
+	}
+}
+
+// Render the screen as plain text
+func (s *vtScreen) text() string {
+	var sb strings.Builder
+	for i, line := range s.cells {
+		var ls strings.Builder
+		for _, c := range line {
+			ls.WriteRune(c.ch)
+		}
+		sb.WriteString(strings.TrimRight(ls.String(), " "))
+		if i < len(s.cells)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// Render the screen as html with the display attributes
+func (s *vtScreen) html() string {
+	var sb strings.Builder
+	sb.WriteString("<pre class=\"console-screen\">")
+	for i, line := range s.cells {
+		// group runs of cells with the same attributes into a single span
+		start := 0
+		for start < len(line) {
+			end := start + 1
+			for end < len(line) && line[end].attr == line[start].attr {
+				end++
+			}
+			var run strings.Builder
+			for _, c := range line[start:end] {
+				run.WriteRune(c.ch)
+			}
+			if style := line[start].attr.style(); style != "" {
+				fmt.Fprintf(&sb, "<span style=\"%s\">%s</span>", style, html.EscapeString(run.String()))
+			} else {
+				sb.WriteString(html.EscapeString(run.String()))
+			}
+			start = end
+		}
+		if i < len(s.cells)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+// Convert the cell attributes into an inline css style
+func (a vtAttr) style() string {
+	fg, bg := a.fg, a.bg
+	if a.bold && fg >= 0 && fg < 8 {
+		// bold text is shown with the bright version of the basic colors
+		fg += 8
+	}
+	var styles []string
+	if a.reverse {
+		// swap the colors, filling in the defaults of a dark terminal
+		if fg < 0 {
+			fg = 7
+		}
+		if bg < 0 {
+			bg = 0
+		}
+		fg, bg = bg, fg
+	}
+	if fg >= 0 {
+		styles = append(styles, "color:"+vtColor(fg))
+	}
+	if bg >= 0 {
+		styles = append(styles, "background-color:"+vtColor(bg))
+	}
+	if a.bold {
+		styles = append(styles, "font-weight:bold")
+	}
+	if a.underline {
+		styles = append(styles, "text-decoration:underline")
+	}
+	if a.blink {
+		styles = append(styles, "text-decoration:blink")
+	}
+	return strings.Join(styles, ";")
+}
+
+// Map a 256 color palette index to an html color
+func vtColor(idx int) string {
+	basic := [16]string{
+		"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+		"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+	}
+	switch {
+	case idx < 16:
+		return basic[idx]
+	case idx < 232:
+		// 6x6x6 color cube
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		idx -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[idx/36], levels[(idx/6)%6], levels[idx%6])
+	default:
+		// grayscale ramp
+		g := 8 + (idx-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", g, g, g)
+	}
+}
+
+// Take a snapshot of the current screen
+func (s *vtScreen) snapshot(xname string) ScreenSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := ScreenSnapshot{
+		Xname:     xname,
+		Rows:      s.rows,
+		Cols:      s.cols,
+		CursorRow: s.curRow,
+		CursorCol: s.curCol,
+		Text:      s.text(),
+		HTML:      s.html(),
+	}
+	if !s.lastUpdate.IsZero() {
+		snap.LastUpdate = s.lastUpdate.Format(time.RFC3339)
+	}
+	return snap
+}
+
+// Undo the 'sanitize' log option of conman so escape sequences can be interpreted
+func desanitizeConsoleText(text string) string {
+	// NOTE: with 'logopts=sanitize' conman writes control characters as '^X'
+	//  which would otherwise show up on the screen as literal text.
+	if !strings.Contains(text, "^") {
+		return text
+	}
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '^' && i+1 < len(text) {
+			next := text[i+1]
+			if next >= '@' && next <= '_' {
+				sb.WriteByte(next - '@')
+				i++
+				continue
+			} else if next == '?' {
+				sb.WriteByte(0x7f)
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(text[i])
+	}
+	return sb.String()
+}
+
+// Start an emulated screen for a console
+func startScreen(xname string) {
+	screensMutex.Lock()
+	defer screensMutex.Unlock()
+	if _, ok := consoleScreens[xname]; !ok {
+		consoleScreens[xname] = newVtScreen(screenRows, screenCols)
+	}
+}
+
+// Remove the emulated screen for a console that is no longer monitored
+func removeScreen(xname string) {
+	screensMutex.Lock()
+	defer screensMutex.Unlock()
+	delete(consoleScreens, xname)
+}
+
+// Look up the screen for a console
+func getScreen(xname string) *vtScreen {
+	screensMutex.Lock()
+	defer screensMutex.Unlock()
+	return consoleScreens[xname]
+}
+
+// Feed a line of console log output to the screen of that console
+func feedScreen(xname, text string) {
+	s := getScreen(xname)
+	if s == nil {
+		return
+	}
+
+	// lines added by conman itself are not part of the console output
+	if isConmanMessage(text) {
+		return
+	}
+	_, text = splitConmanTimestamp(text)
+
+	// the tail strips the line ending, put it back
+	// NOTE: the carriage return is trimmed off before the line gets here, the
+	//  line feed alone would leave the cursor in the same column
+	s.write(desanitizeConsoleText(text) + "\r\n")
+}
+
+// Replay the end of the existing log file so the screen is not blank on startup
+func seedScreen(xname, filename string) {
+	f, err := os.Open(filename)
+	if err != nil {
+		// no file yet is not a problem, the screen will fill as output arrives
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		log.Printf("Unable to stat %s to seed screen: %s", filename, err)
+		return
+	}
+	start := fi.Size() - screenSeedBytes
+	if start < 0 {
+		start = 0
+	}
+	buf := make([]byte, fi.Size()-start)
+	if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+		log.Printf("Unable to read %s to seed screen: %s", filename, err)
+		return
+	}
+
+	lines := strings.Split(string(buf), "\n")
+	if start > 0 && len(lines) > 0 {
+		// the first line is most likely partial
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for _, line := range lines {
+		feedScreen(xname, line)
+	}
+}
+
+// Handle the request for the current screen of a console
+func doConsoleScreen(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	s := getScreen(xname)
+	if s == nil {
+		sendJSONError(w, http.StatusNotFound,
+			fmt.Sprintf("No screen available for console %s", xname))
+		return
+	}
+	snap := s.snapshot(xname)
+
+	// return the format requested - default to json with both renderings
+	switch r.URL.Query().Get("format") {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, snap.Text+"\n")
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, snap.HTML)
+	case "", "json":
+		SendResponseJSON(w, http.StatusOK, snap)
+	default:
+		sendJSONError(w, http.StatusBadRequest,
+			fmt.Sprintf("Unknown format: %s", r.URL.Query().Get("format")))
+	}
+}
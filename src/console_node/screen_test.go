@@ -0,0 +1,61 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the tests of the emulated console screens
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFeedScreenLineStart(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{name: "as seeded", lines: []string{"hello\r", "world\r"}},
+		{name: "as tailed", lines: []string{strings.TrimRight("hello\r", "\r"), strings.TrimRight("world\r", "\r")}},
+		{name: "bare", lines: []string{"hello", "world"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xname := "x3000c0s1b0n0"
+			startScreen(xname)
+			defer removeScreen(xname)
+			for _, line := range tt.lines {
+				feedScreen(xname, line)
+			}
+
+			rows := strings.Split(getScreen(xname).text(), "\n")
+			if rows[0] != "hello" || rows[1] != "world" {
+				t.Errorf("got rows %q and %q, want both to start at column 0", rows[0], rows[1])
+			}
+			if s := getScreen(xname); s.curRow != 2 || s.curCol != 0 {
+				t.Errorf("cursor at %d,%d, want 2,0", s.curRow, s.curCol)
+			}
+		})
+	}
+}
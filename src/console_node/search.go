@@ -0,0 +1,399 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the full text search across the live and rotated
+// console logs of the consoles monitored by this pod
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits on the search operation
+var searchMaxResults int = 1000    // default maximum number of matches returned
+var searchResultsLimit int = 10000 // largest maximum a caller may ask for
+var searchTimeoutSecs int = 30     // maximum time a search may run
+var searchWorkers int = 4          // number of files searched in parallel
+
+// Longest part of a line of console output that is looked at
+const maxLogLineLen int = 64 * 1024
+
+// SearchMatch - a single line that matched a search
+type SearchMatch struct {
+	Xname  string `json:"xname"`
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Time   string `json:"time,omitempty"`
+	Line   string `json:"line"`
+}
+
+// SearchSummary - final record of a search reporting how it completed
+type SearchSummary struct {
+	Matches      int    `json:"matches"`
+	FilesScanned int    `json:"files_scanned"`
+	Truncated    bool   `json:"truncated"`
+	TimedOut     bool   `json:"timed_out"`
+	Elapsed      string `json:"elapsed"`
+}
+
+// The parameters of a single search
+type searchRequest struct {
+	matcher func(line string) bool
+	since   time.Time
+	until   time.Time
+	limit   int
+}
+
+// A single file to search
+type searchFile struct {
+	xname     string
+	logFile   string
+	indexFile string
+}
+
+// Read the search configuration from the environment
+func initSearch() {
+	readSingleEnvVarInt("SEARCH_MAX_RESULTS", &searchMaxResults, 1, searchResultsLimit)
+	readSingleEnvVarInt("SEARCH_TIMEOUT_SEC", &searchTimeoutSecs, 1, 600)
+	readSingleEnvVarInt("SEARCH_WORKERS", &searchWorkers, 1, 32)
+}
+
+// Gather all the files to search for the requested consoles
+func searchFilesFor(xnames []string) []searchFile {
+	var files []searchFile
+	for _, xname := range xnames {
+		for _, lf := range consoleLogFiles(xname) {
+			files = append(files, searchFile{xname: xname, logFile: lf.logFile, indexFile: lf.indexFile})
+
+			// rotation may be configured to compress the old files
+			if _, err := os.Stat(lf.logFile + ".gz"); err == nil {
+				files = append(files, searchFile{xname: xname, logFile: lf.logFile + ".gz", indexFile: lf.indexFile})
+			}
+		}
+	}
+	return files
+}
+
+// Open a log file for reading, uncompressing it if needed
+func openSearchFile(fn string) (io.ReadCloser, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(fn, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// Search a single file sending all matches to the output channel
+func searchOneFile(ctx context.Context, sf searchFile, req *searchRequest, out chan<- SearchMatch) {
+	// skip files that were last written before the time range
+	if !req.since.IsZero() {
+		if fi, err := os.Stat(sf.logFile); err == nil && fi.ModTime().Before(req.since) {
+			return
+		}
+	}
+
+	// the time index gives the receive time of the lines and where to start
+	entries, _ := readTimeIndex(sf.indexFile)
+	compressed := strings.HasSuffix(sf.logFile, ".gz")
+
+	rc, err := openSearchFile(sf.logFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Search unable to open %s: %s", sf.logFile, err)
+		}
+		return
+	}
+	defer rc.Close()
+
+	var offset int64 = 0
+	if !req.since.IsZero() && len(entries) > 0 && !compressed {
+		if f, ok := rc.(*os.File); ok {
+			offset = indexStartOffset(entries, req.since)
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				offset = 0
+				f.Seek(0, io.SeekStart)
+			}
+		}
+	}
+
+	rd := bufio.NewReaderSize(rc, 64*1024)
+	var markTime time.Time
+	for ctx.Err() == nil {
+		line, n, err := readLogLine(rd)
+		if n > 0 {
+			text := strings.TrimRight(line, "\n")
+
+			// figure out when this line was received as best we can, the index
+			// only brackets the time of lines between its entries
+			when, latest := indexTimeRange(entries, offset)
+			if ts, _ := splitConmanTimestamp(text); !ts.IsZero() {
+				// conman writes whole seconds
+				when, latest = ts, ts.Add(time.Second)
+			} else if ts := conmanMarkTime(text); !ts.IsZero() {
+				markTime = ts
+			}
+			if when.IsZero() {
+				when = markTime
+			}
+
+			if !when.IsZero() && !req.until.IsZero() && when.After(req.until) {
+				return
+			}
+			// NOTE: lines with no known time can not be placed in a time range
+			inRange := true
+			if !req.since.IsZero() || !req.until.IsZero() {
+				inRange = !when.IsZero()
+			}
+			if !req.since.IsZero() && !latest.IsZero() && !latest.After(req.since) {
+				inRange = false
+			}
+			if inRange && req.matcher(text) {
+				m := SearchMatch{Xname: sf.xname, File: sf.logFile, Offset: offset, Line: text}
+				if !when.IsZero() {
+					m.Time = when.Format(time.RFC3339Nano)
+				}
+				select {
+				case out <- m:
+				case <-ctx.Done():
+					return
+				}
+			}
+			offset += n
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Search error reading %s: %s", sf.logFile, err)
+			}
+			return
+		}
+	}
+}
+
+// Read a line of a console log, returning at most maxLogLineLen bytes of it and
+// the number of bytes the whole line takes up in the file
+func readLogLine(rd *bufio.Reader) (string, int64, error) {
+	// NOTE: a console flooding without newlines must not be read into memory
+	//  whole, the rest of a long line is read past and dropped
+	var line []byte
+	var n int64
+	for {
+		part, err := rd.ReadSlice('\n')
+		n += int64(len(part))
+		if room := maxLogLineLen - len(line); room > 0 {
+			line = append(line, part[:min(len(part), room)]...)
+		}
+		if err != bufio.ErrBufferFull {
+			return string(line), n, err
+		}
+	}
+}
+
+// Pull the time out of the periodic conman 'log at' marker lines
+func conmanMarkTime(text string) time.Time {
+	// NOTE: with 'timestamp=1h' conman writes lines of the form:
+	//  <ConMan> Console [xname] log at 2024-01-02 03:04:05 CST.
+	if !isConmanMessage(text) {
+		return time.Time{}
+	}
+	pos := strings.Index(text, " log at ")
+	if pos < 0 {
+		return time.Time{}
+	}
+	rest := text[pos+len(" log at "):]
+	if len(rest) < len(conmanTimestampLayout) {
+		return time.Time{}
+	}
+	ts, err := time.ParseInLocation(conmanTimestampLayout, rest[:len(conmanTimestampLayout)], time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
+
+// Run a search across the given files, handing each match to the handler
+func runSearch(ctx context.Context, files []searchFile, req *searchRequest, handler func(m SearchMatch) bool) (summary SearchSummary) {
+	startTime := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// bounded set of workers pulling files to search
+	work := make(chan searchFile)
+	out := make(chan SearchMatch, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < searchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sf := range work {
+				searchOneFile(ctx, sf, req, out)
+			}
+		}()
+	}
+	go func() {
+		defer close(work)
+		for _, sf := range files {
+			select {
+			case work <- sf:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	summary.FilesScanned = len(files)
+	for m := range out {
+		if summary.Matches >= req.limit {
+			summary.Truncated = true
+			cancel()
+			continue
+		}
+		summary.Matches++
+		if !handler(m) {
+			cancel()
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		summary.TimedOut = true
+	}
+	summary.Elapsed = time.Since(startTime).String()
+	return summary
+}
+
+// Handle a search across the console logs
+func doSearch(w http.ResponseWriter, r *http.Request) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	// gather the search parameters
+	q := r.URL.Query()
+	query := q.Get("q")
+	if query == "" {
+		sendJSONError(w, http.StatusBadRequest, "Missing search query 'q'")
+		return
+	}
+	req := &searchRequest{limit: searchMaxResults}
+	var err error
+	if req.since, err = parseTimeParam(q.Get("since")); err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since: %s", err))
+		return
+	}
+	if req.until, err = parseTimeParam(q.Get("until")); err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid until: %s", err))
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit: %s", v))
+			return
+		}
+		if limit > searchResultsLimit {
+			limit = searchResultsLimit
+		}
+		req.limit = limit
+	}
+	if isTrue(q.Get("regex")) {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid regex: %s", err))
+			return
+		}
+		req.matcher = re.MatchString
+	} else {
+		req.matcher = func(line string) bool { return strings.Contains(line, query) }
+	}
+
+	// only the consoles monitored by this pod may be searched
+	owned := authFilterXnames(r, getCurrNodeXnames())
+	xnames := owned
+	if v := q.Get("xnames"); v != "" {
+		ownedSet := make(map[string]bool)
+		for _, xn := range owned {
+			ownedSet[xn] = true
+		}
+		xnames = nil
+		for _, xn := range strings.Split(v, ",") {
+			xn = strings.TrimSpace(xn)
+			if ownedSet[xn] {
+				xnames = append(xnames, xn)
+			}
+		}
+	}
+
+	// stream the matches out as they are found
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(searchTimeoutSecs)*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	summary := runSearch(ctx, searchFilesFor(xnames), req, func(m SearchMatch) bool {
+		if err := enc.Encode(m); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	})
+
+	// finish with a summary so the caller knows if the results are complete
+	enc.Encode(struct {
+		Summary SearchSummary `json:"summary"`
+	}{summary})
+}
@@ -0,0 +1,624 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the in process simulation of the services console-node
+// works with and of the consoles themselves
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	mrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/ssh"
+
+	compcreds "github.com/Cray-HPE/hms-compcredentials"
+)
+
+// Run against the simulated services instead of the real ones
+var simulating bool = false
+
+// Size of the simulated system
+var simRvrNodes int = 12
+var simMtnNodes int = 8
+var simPdsNodes int = 2
+
+// Timing of the simulated system
+var simChurnSec int = 300      // how often console-data moves a node away, 0 for never
+var simCredsDelaySec int = 60  // how long some BMCs are missing credentials
+var simRebootSec int = 1800    // average time between reboots of a node
+var simVaultTokenSec int = 600 // lifetime of the simulated vault tokens
+
+// Worker node the simulated pod runs on
+const simWorkerXname string = "x3000c0s99b0n0"
+
+// Check if running against the simulated services
+func simulationMode() bool {
+	return simulating
+}
+
+// simCluster - the nodes of the simulated system and the pods watching them
+type simCluster struct {
+	mu        sync.Mutex
+	nodes     []nodeConsoleInfo
+	owner     map[string]string // [xname,podID]
+	started   time.Time
+	lastChurn time.Time
+}
+
+// The simulated system
+var simulation *simCluster = nil
+
+// Build the nodes of the simulated system
+func newSimCluster() *simCluster {
+	sc := &simCluster{
+		owner:     make(map[string]string),
+		started:   time.Now(),
+		lastChurn: time.Now(),
+	}
+	for i := 0; i < simRvrNodes; i++ {
+		bmc := fmt.Sprintf("x3000c0s%db0", i+1)
+		sc.nodes = append(sc.nodes, nodeConsoleInfo{NodeName: bmc + "n0", BmcName: bmc, BmcFqdn: bmc,
+			Class: "River", NID: i + 1, Role: "Compute"})
+	}
+	for i := 0; i < simMtnNodes; i++ {
+		// two nodes share each Mountain node card bmc
+		bmc := fmt.Sprintf("x1000c%ds%db0", i/16, (i/2)%8)
+		sc.nodes = append(sc.nodes, nodeConsoleInfo{NodeName: fmt.Sprintf("%sn%d", bmc, i%2), BmcName: bmc,
+			BmcFqdn: bmc, Class: "Mountain", NID: 1000 + i, Role: "Compute"})
+	}
+	for i := 0; i < simPdsNodes; i++ {
+		bmc := fmt.Sprintf("x3001c0s%db0", i+1)
+		sc.nodes = append(sc.nodes, nodeConsoleInfo{NodeName: bmc + "n0", BmcName: bmc, BmcFqdn: bmc,
+			Class: "Paradise", NID: 2000 + i, Role: "Compute"})
+	}
+	return sc
+}
+
+// Set up the simulated services in place of the real ones
+func startSimulation() {
+	readSingleEnvVarInt("SIM_RVR_NODES", &simRvrNodes, 0, 4000)
+	readSingleEnvVarInt("SIM_MTN_NODES", &simMtnNodes, 0, 2000)
+	readSingleEnvVarInt("SIM_PDS_NODES", &simPdsNodes, 0, 2000)
+	readSingleEnvVarInt("SIM_CHURN_SEC", &simChurnSec, 0, 86400)
+	readSingleEnvVarInt("SIM_CREDS_DELAY_SEC", &simCredsDelaySec, 0, 3600)
+	readSingleEnvVarInt("SIM_REBOOT_SEC", &simRebootSec, 60, 86400)
+	readSingleEnvVarInt("SIM_VAULT_TOKEN_SEC", &simVaultTokenSec, 30, 86400)
+
+	simulation = newSimCluster()
+	opService = SimOperator{cluster: simulation}
+	dataService = SimConsoleData{cluster: simulation}
+	vaultSession = newSimVault()
+	log.Printf("Simulating River:%d, Mountain:%d, Paradise:%d nodes", simRvrNodes, simMtnNodes, simPdsNodes)
+}
+
+// SimOperator - simulated console-operator
+type SimOperator struct {
+	cluster *simCluster
+}
+
+// OperatorRetryInterval returns the retry interval for the operator.
+func (so SimOperator) OperatorRetryInterval() time.Duration {
+	return time.Second
+}
+
+// The simulated pod always runs on the same worker
+func (so SimOperator) getPodLocation(podID string) (*PodLocationDataResponse, error) {
+	return &PodLocationDataResponse{PodName: podID, Alias: "ncn-w001", Xname: simWorkerXname}, nil
+}
+
+// Every node of the simulated system is a target of the single pod
+func (so SimOperator) getCurrentTargets() (*CurrentTargets, error) {
+	targets := &CurrentTargets{TargetNumNodePods: 1}
+	for _, node := range so.cluster.nodes {
+		if node.isRiver() {
+			targets.TotalRvrNodes++
+		} else {
+			targets.TotalMtnNodes++
+		}
+	}
+	targets.TargetNumRvrNodes = targets.TotalRvrNodes
+	targets.TargetNumMtnNodes = targets.TotalMtnNodes
+	return targets, nil
+}
+
+// SimConsoleData - simulated console-data
+type SimConsoleData struct {
+	cluster *simCluster
+}
+
+// Only this pod is running
+func (sd SimConsoleData) getNumActiveNodePods() (int, error) {
+	return 1, nil
+}
+
+// Hand out nodes no pod is watching
+func (sd SimConsoleData) acquireNewNodes(numMtn, numRvr int, podLocation *PodLocationDataResponse) []nodeConsoleInfo {
+	log.Printf("Acquiring new simulated nodes mtn: %d, rvr: %d", numMtn, numRvr)
+	sd.cluster.mu.Lock()
+	defer sd.cluster.mu.Unlock()
+
+	// NOTE: paradise nodes are included in mountain count
+	var newNodes []nodeConsoleInfo
+	for _, node := range sd.cluster.nodes {
+		if _, ok := sd.cluster.owner[node.NodeName]; ok || node.NodeName == podLocation.Xname {
+			continue
+		}
+		if node.isRiver() && numRvr > 0 {
+			numRvr--
+		} else if !node.isRiver() && numMtn > 0 {
+			numMtn--
+		} else {
+			continue
+		}
+		sd.cluster.owner[node.NodeName] = podID
+		newNodes = append(newNodes, node)
+	}
+	return newNodes
+}
+
+// Record the nodes of the pod, now and then moving one of them away
+func (sd SimConsoleData) sendHeartbeat(heartBeatPayload nodeConsoleInfoHeartBeat) ([]nodeConsoleInfo, error) {
+	sd.cluster.mu.Lock()
+	defer sd.cluster.mu.Unlock()
+
+	for _, ni := range heartBeatPayload.CurrNodes {
+		sd.cluster.owner[ni.NodeName] = podID
+	}
+	if simChurnSec == 0 || len(heartBeatPayload.CurrNodes) == 0 ||
+		time.Since(sd.cluster.lastChurn) < time.Duration(simChurnSec)*time.Second {
+		return nil, nil
+	}
+
+	// give a node to another pod, it comes back to be acquired again
+	sd.cluster.lastChurn = time.Now()
+	ni := heartBeatPayload.CurrNodes[mrand.Intn(len(heartBeatPayload.CurrNodes))]
+	delete(sd.cluster.owner, ni.NodeName)
+	log.Printf("Simulated console-data moving %s to another pod", ni.NodeName)
+	return []nodeConsoleInfo{{NodeName: ni.NodeName, BmcName: ni.BmcName, BmcFqdn: ni.BmcFqdn,
+		Class: ni.Class, NID: ni.NID, Role: ni.Role}}, nil
+}
+
+// Mark the nodes as no longer watched
+func (sd SimConsoleData) releaseNodes(nodes []nodeConsoleInfo) error {
+	sd.cluster.mu.Lock()
+	defer sd.cluster.mu.Unlock()
+	for _, ni := range nodes {
+		if sd.cluster.owner[ni.NodeName] == podID {
+			delete(sd.cluster.owner, ni.NodeName)
+		}
+	}
+	return nil
+}
+
+// SimCredManager - simulated BMC credentials
+// NOTE: every fourth River bmc has no credentials until SIM_CREDS_DELAY_SEC
+// have passed so the pending credential handling is exercised
+type SimCredManager struct {
+	cluster *simCluster
+}
+
+// Name of the provider
+func (sm *SimCredManager) Name() string {
+	return "sim"
+}
+
+// Make up the credentials of the simulated BMCs
+func (sm *SimCredManager) lookupCredentials(bmcXNames []string) map[string]credResult {
+	late := time.Since(sm.cluster.started) < time.Duration(simCredsDelaySec)*time.Second
+	results := make(map[string]credResult)
+	for _, bmc := range bmcXNames {
+		var slot int
+		if _, err := fmt.Sscanf(bmc, "x3000c0s%db0", &slot); late && err == nil && slot%4 == 0 {
+			results[bmc] = credResult{err: errCredsNotFound}
+			continue
+		}
+		results[bmc] = credResult{creds: compcreds.CompCredentials{Xname: bmc, Username: "root", Password: "sim-" + bmc}}
+	}
+	return results
+}
+
+// simVault - in process stand in for the parts of vault used here
+type simVault struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time // [token,expires]
+	key    []byte               // Mountain console private key
+	signer *LocalSSHSigner      // ssh secrets engine
+}
+
+// Start the simulated vault and a session using it
+func newSimVault() *VaultSession {
+	sv := &simVault{tokens: make(map[string]time.Time)}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err == nil {
+		var block *pem.Block
+		if block, err = ssh.MarshalPrivateKey(key, "console-node simulation"); err == nil {
+			sv.key = pem.EncodeToMemory(block)
+		}
+	}
+	_, caKey, caErr := ed25519.GenerateKey(rand.Reader)
+	if caErr == nil {
+		var ca ssh.Signer
+		if ca, caErr = ssh.NewSignerFromKey(caKey); caErr == nil {
+			sv.signer = &LocalSSHSigner{ca: ca}
+		}
+	}
+	if err != nil || caErr != nil {
+		log.Printf("Unable to create the simulated vault keys: %v %v", err, caErr)
+	}
+
+	srv := httptest.NewServer(sv)
+	log.Printf("Simulated vault listening at %s", srv.URL)
+	return &VaultSession{
+		baseURL: srv.URL + "/v1",
+		auth:    &VaultAppRoleAuth{mount: "approle", roleID: "console-node-simulation"},
+		client:  srv.Client(),
+	}
+}
+
+// Send a json response from the simulated vault
+func simVaultReply(w http.ResponseWriter, sc int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(sc)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Hand out a new token
+func (sv *simVault) newToken() map[string]interface{} {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := fmt.Sprintf("hvs.sim%x", buf)
+	sv.mu.Lock()
+	sv.tokens[token] = time.Now().Add(time.Duration(simVaultTokenSec) * time.Second)
+	sv.mu.Unlock()
+	return map[string]interface{}{"auth": map[string]interface{}{
+		"client_token": token, "lease_duration": simVaultTokenSec, "renewable": true}}
+}
+
+// Check the token of a request is one handed out and not expired
+func (sv *simVault) validToken(token string) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	expires, ok := sv.tokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+// Handle the requests made to vault
+func (sv *simVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	if r.Method == http.MethodPost && strings.HasPrefix(path, "/auth/") && strings.HasSuffix(path, "/login") {
+		simVaultReply(w, http.StatusOK, sv.newToken())
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if !sv.validToken(token) {
+		simVaultReply(w, http.StatusForbidden, map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+	switch {
+	case path == "/auth/token/renew-self":
+		sv.mu.Lock()
+		sv.tokens[token] = time.Now().Add(time.Duration(simVaultTokenSec) * time.Second)
+		sv.mu.Unlock()
+		simVaultReply(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{
+			"client_token": token, "lease_duration": simVaultTokenSec, "renewable": true}})
+	case path == "/auth/token/lookup-self":
+		simVaultReply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"ttl": simVaultTokenSec, "renewable": true}})
+	case strings.Contains(path, "/export/signing-key/") && sv.key != nil:
+		simVaultReply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"keys": map[string]string{"1": string(sv.key)}}})
+	case strings.HasPrefix(path, "/ssh/sign/") && sv.signer != nil:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			simVaultReply(w, http.StatusBadRequest, map[string][]string{"errors": {err.Error()}})
+			return
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(gjson.GetBytes(body, "public_key").String()))
+		if err != nil {
+			simVaultReply(w, http.StatusBadRequest, map[string][]string{"errors": {err.Error()}})
+			return
+		}
+		ttl, err := time.ParseDuration(gjson.GetBytes(body, "ttl").String())
+		if err != nil {
+			ttl = time.Hour
+		}
+		cert, err := sv.signer.signKey(pub, ttl)
+		if err != nil {
+			simVaultReply(w, http.StatusInternalServerError, map[string][]string{"errors": {err.Error()}})
+			return
+		}
+		simVaultReply(w, http.StatusOK, map[string]interface{}{"data": map[string]string{
+			"signed_key": string(ssh.MarshalAuthorizedKey(cert))}})
+	default:
+		simVaultReply(w, http.StatusNotFound, map[string][]string{"errors": {}})
+	}
+}
+
+// simConsole - a simulated node writing to its console log in place of conmand
+type simConsole struct {
+	node nodeConsoleInfo
+	stop chan struct{}
+	rnd  *mrand.Rand
+	boot time.Time
+	err  bool // a write error has already been logged
+
+	atPrompt bool // the last write was a prompt without a newline
+}
+
+// Globals holding the simulated consoles
+var simConsolesMutex = &sync.Mutex{}
+var simConsoles map[string]*simConsole = make(map[string]*simConsole) // [xname,*simConsole]
+var simConmanRestart = make(chan struct{}, 1)
+
+// Run the simulated consoles until conmand would be restarted
+func runSimConsoles() {
+	// NOTE: in update config thread
+	syncSimConsoles()
+	<-simConmanRestart
+}
+
+// Ask for the simulated consoles to be reconfigured, as conmand would be on SIGTERM
+func restartSimConsoles() {
+	select {
+	case simConmanRestart <- struct{}{}:
+	default:
+	}
+}
+
+// Match the simulated consoles to the consoles conman would connect to
+func syncSimConsoles() {
+	currNodesMutex.Lock()
+	var nodes []nodeConsoleInfo
+	allNodes := [3](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes, &currentMtnNodes}
+	for _, ar := range allNodes {
+		for _, ni := range *ar {
+			nodes = append(nodes, *ni)
+		}
+	}
+	currNodesMutex.Unlock()
+
+	// consoles still waiting for credentials or a tenant are not connected
+	wanted := make(map[string]nodeConsoleInfo)
+	for _, node := range nodes {
+		if !credentialsPending(node.NodeName) && !tenantPending(node.NodeName) {
+			wanted[node.NodeName] = node
+		}
+	}
+
+	simConsolesMutex.Lock()
+	defer simConsolesMutex.Unlock()
+	for xname, sc := range simConsoles {
+		if _, ok := wanted[xname]; !ok {
+			close(sc.stop)
+			delete(simConsoles, xname)
+		}
+	}
+	for xname, node := range wanted {
+		if _, ok := simConsoles[xname]; !ok {
+			sc := &simConsole{
+				node: node,
+				stop: make(chan struct{}),
+				rnd:  mrand.New(mrand.NewSource(time.Now().UnixNano() + int64(node.NID))),
+			}
+			simConsoles[xname] = sc
+			go sc.run()
+		}
+	}
+	log.Printf("Running %d simulated consoles", len(simConsoles))
+}
+
+// Wait for a while, returns false if the console was stopped
+func (sc *simConsole) pause(d time.Duration) bool {
+	select {
+	case <-sc.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Wait for a random time between min and max
+func (sc *simConsole) pauseBetween(min, max time.Duration) bool {
+	return sc.pause(min + time.Duration(sc.rnd.Int63n(int64(max-min)+1)))
+}
+
+// Add lines to the console log, ended with '\r\n' like a serial console
+func (sc *simConsole) write(lines ...string) {
+	var sb strings.Builder
+	if sc.atPrompt {
+		// output after a prompt starts on a new line
+		sb.WriteString("\r\n")
+	}
+	for _, line := range lines {
+		sb.WriteString(line + "\r\n")
+	}
+	if sc.appendLog(sb.String()) {
+		sc.atPrompt = false
+	}
+}
+
+// Add a prompt to the console log, left without a newline as conmand does
+func (sc *simConsole) writePrompt(prompt string) {
+	if sc.appendLog(prompt) {
+		sc.atPrompt = true
+	}
+}
+
+// Append output to the console log, returns if it was written
+// NOTE: the file is opened for each write so rotation is followed as conmand does
+func (sc *simConsole) appendLog(data string) bool {
+	f, err := os.OpenFile(consoleLogFile(sc.node.NodeName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFileMode)
+	if err != nil {
+		if !sc.err {
+			log.Printf("Unable to write the simulated console of %s: %s", sc.node.NodeName, err)
+			sc.err = true
+		}
+		return false
+	}
+	defer f.Close()
+	sc.err = false
+	_, err = f.WriteString(data)
+	return err == nil
+}
+
+// Kernel message with the time since boot
+func (sc *simConsole) kmsg(format string, a ...interface{}) string {
+	up := time.Since(sc.boot)
+	return fmt.Sprintf("[%5d.%06d] ", int64(up/time.Second), int64(up%time.Second/time.Microsecond)) +
+		fmt.Sprintf(format, a...)
+}
+
+// Host name of the simulated node
+func (sc *simConsole) hostname() string {
+	return fmt.Sprintf("nid%06d", sc.node.NID)
+}
+
+// Keep the node booting, running, and rebooting until it is stopped
+func (sc *simConsole) run() {
+	// the nodes do not all come up at once
+	if !sc.pauseBetween(0, 10*time.Second) {
+		return
+	}
+	for {
+		if !sc.bootNode() {
+			return
+		}
+		half := time.Duration(simRebootSec) * time.Second / 2
+		rebootAt := time.Now().Add(half + time.Duration(sc.rnd.Int63n(int64(2*half))))
+		for time.Now().Before(rebootAt) {
+			if !sc.pauseBetween(time.Second, 8*time.Second) {
+				return
+			}
+			sc.write(sc.runtimeLines()...)
+		}
+		sc.write(sc.kmsg("reboot: Restarting system"))
+		if !sc.pauseBetween(2*time.Second, 5*time.Second) {
+			return
+		}
+	}
+}
+
+// Write out a boot from firmware to the login prompt, returns false if stopped
+func (sc *simConsole) bootNode() bool {
+	const kernel string = "5.14.21-150500.55.49-default"
+	sc.boot = time.Now()
+	firmware := []string{
+		"BIOS Version: 2.8.2 Date: 03/14/2025",
+		"POST: memory test complete, 512 GB",
+		"Press <DEL> to enter setup, <F11> for boot menu",
+		"iPXE 1.21.1+ (g8c7f9) -- Open Source Network Boot Firmware -- https://ipxe.org",
+		"Booting from http://api-gw-service-nmn.local/apis/bss/boot/v1/bootscript",
+		"Loading Linux " + kernel + " ...",
+	}
+	for _, line := range firmware {
+		sc.write(line)
+		if !sc.pauseBetween(500*time.Millisecond, 3*time.Second) {
+			return false
+		}
+	}
+
+	sc.boot = time.Now()
+	sc.write(
+		sc.kmsg("Linux version %s (geeko@buildhost) (gcc (SUSE Linux) 7.5.0, GNU ld (GNU Binutils; SUSE Linux Enterprise 15) 2.41) #1 SMP PREEMPT_DYNAMIC", kernel),
+		sc.kmsg("Command line: BOOT_IMAGE=/vmlinuz console=ttyS0,115200"),
+		sc.kmsg("Kernel command line: BOOT_IMAGE=/vmlinuz console=ttyS0,115200 xname=%s nid=%d hostname=%s",
+			sc.node.NodeName, sc.node.NID, sc.hostname()),
+		sc.kmsg("smpboot: Allowing 128 CPUs, 0 hotplug CPUs"))
+	kernelBoot := []string{
+		"Trying to unpack rootfs image as initramfs...",
+		"Freeing initrd memory: 412332K",
+		"Run /init as init process",
+		"systemd[1]: Switching root.",
+		"systemd[1]: Reached target Basic System.",
+		"systemd[1]: Reached target Multi-User System.",
+	}
+	for _, line := range kernelBoot {
+		if !sc.pauseBetween(time.Second, 5*time.Second) {
+			return false
+		}
+		sc.write(sc.kmsg("%s", line))
+	}
+	sc.write("",
+		"Welcome to SUSE Linux Enterprise Server 15 SP5  (x86_64) - Kernel "+kernel+" (ttyS0).",
+		"")
+	sc.writePrompt(sc.hostname() + " login: ")
+	return true
+}
+
+// Lines written while the node is up
+func (sc *simConsole) runtimeLines() []string {
+	roll := sc.rnd.Intn(1000)
+	switch {
+	case roll < 5:
+		// a soft lockup with its stack trace
+		cpu := sc.rnd.Intn(128)
+		lines := []string{
+			sc.kmsg("watchdog: BUG: soft lockup - CPU#%d stuck for %ds! [kworker/%d:1:%d]", cpu, 22+sc.rnd.Intn(20), cpu, 1000+sc.rnd.Intn(30000)),
+			sc.kmsg("CPU: %d PID: %d Comm: kworker/%d:1 Tainted: G             L     5.14.21-150500.55.49-default #1", cpu, 1000+sc.rnd.Intn(30000), cpu),
+			sc.kmsg("Call Trace:"),
+		}
+		for _, fn := range []string{"<IRQ>", "__schedule+0x2f3/0x8a0", "schedule+0x46/0xb0", "worker_thread+0xb4/0x3c0",
+			"kthread+0x156/0x180", "ret_from_fork+0x22/0x30", "</IRQ>"} {
+			lines = append(lines, sc.kmsg(" %s", fn))
+		}
+		return lines
+	case roll < 10:
+		return []string{sc.kmsg("mce: [Hardware Error]: CPU %d: Machine Check: 0 Bank %d: dc2040000000011b", sc.rnd.Intn(128), sc.rnd.Intn(24))}
+	case roll < 20:
+		return []string{sc.kmsg("EDAC MC%d: 1 CE memory read error on CPU_SrcID#0_MC#%d_Chan#%d_DIMM#0 (channel:%d slot:0 page:0x%x offset:0x0 grain:32 syndrome:0x0)",
+			sc.rnd.Intn(2), sc.rnd.Intn(2), sc.rnd.Intn(4), sc.rnd.Intn(4), sc.rnd.Intn(0xfffff))}
+	case roll < 25:
+		return []string{sc.kmsg("pcieport 0000:%02x:03.1: PCIe Bus Error: severity=Corrected, type=Physical Layer, (Receiver ID)", sc.rnd.Intn(0x80))}
+	case roll < 28:
+		return []string{sc.kmsg("nvme nvme0: I/O %d QID %d timeout, aborting", sc.rnd.Intn(1024), 1+sc.rnd.Intn(16))}
+	case roll < 32 && sc.node.isMountain():
+		return []string{sc.kmsg("cxi_core 0000:41:00.0: cxi0[hsn0]: link down")}
+	}
+	messages := []string{
+		"perf: interrupt took too long (2510 > 2500), lowering kernel.perf_event_max_sample_rate to 79500",
+		"Lustre: lus-OST0003-osc-ffff9a0b4c1e8000: Connection restored to 10.150.0.14@o2ib (at 10.150.0.14@o2ib)",
+		"hsn0: link becomes ready",
+		"systemd[1]: Started Session 12 of user root.",
+		"systemd[1]: Starting Cleanup of Temporary Directories...",
+		"audit: type=1400 audit(1760832000.123:42): apparmor=\"STATUS\" operation=\"profile_replace\"",
+		"device-mapper: uevent: version 1.0.3",
+		"NFS: Registering the id_resolver key type",
+	}
+	return []string{sc.kmsg("%s", messages[sc.rnd.Intn(len(messages))])}
+}
@@ -0,0 +1,406 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the short lived ssh certificates used for Mountain consoles
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/ssh"
+)
+
+// Directory holding the current Mountain console certificate
+// NOTE: 'current' is a symlink to a directory with the key and its certificate
+// so ssh-key-console always sees a matching pair, even mid rotation
+const mountainCertDir string = "/app/conman-cert"
+const mountainCertKeyName string = "id_ed25519"
+
+// How the Mountain consoles authenticate, 'key' or 'cert'
+var mountainAuthMode string = "key"
+
+// Requested lifetime of the certificates
+var mountainCertTTLSecs int = 3600
+
+// SSHCertSigner - interface for signing the ephemeral console keys
+type SSHCertSigner interface {
+	signKey(pub ssh.PublicKey, ttl time.Duration) (*ssh.Certificate, error)
+	Name() string
+}
+
+// NewSSHCertSigner creates the certificate signer selected by the environment
+func NewSSHCertSigner() SSHCertSigner {
+	principals := splitCommaList(os.Getenv("MOUNTAIN_CERT_PRINCIPALS"))
+	if strings.ToLower(os.Getenv("MOUNTAIN_CERT_SIGNER")) == "local" {
+		caFile := os.Getenv("MOUNTAIN_CERT_CA_KEY")
+		if caFile == "" {
+			caFile = "/app/mountain-ca.key"
+		}
+		log.Printf("Signing Mountain console certificates locally with %s", caFile)
+		return &LocalSSHSigner{caKeyFile: caFile, principals: principals}
+	}
+	signPath := os.Getenv("MOUNTAIN_CERT_SIGN_PATH")
+	if signPath == "" {
+		signPath = "/ssh/sign/ssh-user-certs-compute"
+	}
+	log.Printf("Signing Mountain console certificates with vault %s", signPath)
+	return &VaultSSHSigner{signPath: signPath, principals: principals}
+}
+
+// Split a comma separated list, dropping empty entries
+func splitCommaList(val string) []string {
+	var list []string
+	for _, v := range strings.Split(val, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// VaultSSHSigner - certificates signed by the vault ssh secrets engine
+type VaultSSHSigner struct {
+	signPath   string
+	principals []string
+}
+
+// Name of the signer
+func (vs *VaultSSHSigner) Name() string {
+	return "vault"
+}
+
+// Have vault sign the public key
+func (vs *VaultSSHSigner) signKey(pub ssh.PublicKey, ttl time.Duration) (*ssh.Certificate, error) {
+	req := map[string]string{
+		"public_key": string(ssh.MarshalAuthorizedKey(pub)),
+		"cert_type":  "user",
+		"ttl":        fmt.Sprintf("%ds", int(ttl.Seconds())),
+	}
+	if len(vs.principals) > 0 {
+		req["valid_principals"] = strings.Join(vs.principals, ",")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	response, sc, err := vaultSession.request(http.MethodPost, vs.signPath, body)
+	if err != nil {
+		return nil, err
+	}
+	if sc != http.StatusOK {
+		return nil, fmt.Errorf("vault ssh signing failed, sc=%d", sc)
+	}
+	signed := gjson.GetBytes(response, "data.signed_key").String()
+	return parseSSHCert([]byte(signed))
+}
+
+// LocalSSHSigner - certificates signed with a local CA key for testing
+// NOTE: the CA key is generated if the file does not exist, its public key is
+// written next to it for the test BMCs to trust
+type LocalSSHSigner struct {
+	caKeyFile  string
+	principals []string
+	mu         sync.Mutex
+	ca         ssh.Signer
+}
+
+// Name of the signer
+func (ls *LocalSSHSigner) Name() string {
+	return "local"
+}
+
+// Load or create the CA key
+func (ls *LocalSSHSigner) loadCA() (ssh.Signer, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.ca != nil {
+		return ls.ca, nil
+	}
+	data, err := os.ReadFile(ls.caKeyFile)
+	if os.IsNotExist(err) {
+		log.Printf("Creating the local Mountain console CA key %s", ls.caKeyFile)
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "console-node local ca")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.WriteFile(ls.caKeyFile, data, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	ca, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA key %s: %s", ls.caKeyFile, err)
+	}
+	if err := os.WriteFile(ls.caKeyFile+".pub", ssh.MarshalAuthorizedKey(ca.PublicKey()), 0644); err != nil {
+		log.Printf("Unable to write the local CA public key: %s", err)
+	}
+	ls.ca = ca
+	return ca, nil
+}
+
+// Sign the public key with the local CA
+func (ls *LocalSSHSigner) signKey(pub ssh.PublicKey, ttl time.Duration) (*ssh.Certificate, error) {
+	ca, err := ls.loadCA()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             pub,
+		Serial:          uint64(now.UnixNano()),
+		KeyId:           "console-node-" + podName,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: ls.principals,
+		ValidAfter:      uint64(now.Add(-5 * time.Minute).Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+		Permissions:     ssh.Permissions{Extensions: map[string]string{"permit-pty": ""}},
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Parse a certificate in the authorized keys format
+func parseSSHCert(data []byte) (*ssh.Certificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh certificate: %s", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("signed key is not an ssh certificate")
+	}
+	return cert, nil
+}
+
+// MountainCertStatus - state of the Mountain console certificate
+type MountainCertStatus struct {
+	Signer      string `json:"signer"`
+	Serial      uint64 `json:"serial"`
+	ValidBefore string `json:"valid_before,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// Globals holding the state of the certificate
+var mountainCertMutex = &sync.Mutex{}
+var mountainCertSigner SSHCertSigner = nil
+var mountainCert *ssh.Certificate = nil
+var mountainCertIssued time.Time
+var mountainCertError string = ""
+
+// Only one rotation at a time, held across deciding to rotate and the rotation
+// NOTE: separate from mountainCertMutex so the state can be read while signing
+var mountainCertRotateMutex = &sync.Mutex{}
+
+// Set up the Mountain console authentication mode
+func initMountainAuth() {
+	if val := os.Getenv("MOUNTAIN_AUTH_MODE"); val != "" {
+		mountainAuthMode = strings.ToLower(val)
+	}
+	readSingleEnvVarInt("MOUNTAIN_CERT_TTL_SEC", &mountainCertTTLSecs, 300, 7*86400)
+	if mountainAuthMode != "cert" {
+		// ssh-key-console uses the certificate whenever it is present
+		os.RemoveAll(mountainCertDir)
+		return
+	}
+	log.Printf("Mountain consoles use short lived ssh certificates")
+	mountainCertSigner = NewSSHCertSigner()
+	go watchMountainCert()
+}
+
+// Check if the certificate should be replaced
+func mountainCertDue() bool {
+	mountainCertMutex.Lock()
+	defer mountainCertMutex.Unlock()
+	if mountainCert == nil {
+		return true
+	}
+	// replace it once two thirds of its lifetime have gone by
+	expires := time.Unix(int64(mountainCert.ValidBefore), 0)
+	return time.Now().After(mountainCertIssued.Add(expires.Sub(mountainCertIssued) * 2 / 3))
+}
+
+// Make sure there is a valid certificate for the Mountain consoles
+func ensureMountainCert() error {
+	mountainCertRotateMutex.Lock()
+	defer mountainCertRotateMutex.Unlock()
+	if !mountainCertDue() {
+		return nil
+	}
+	err := rotateMountainCert()
+	mountainCertMutex.Lock()
+	defer mountainCertMutex.Unlock()
+	if err != nil {
+		mountainCertError = err.Error()
+		// carry on with the old certificate while it is still valid
+		if mountainCert != nil && time.Now().Before(time.Unix(int64(mountainCert.ValidBefore), 0)) {
+			return nil
+		}
+		return err
+	}
+	mountainCertError = ""
+	return nil
+}
+
+// Create a new key, have it signed, and swap it in for the current one
+func rotateMountainCert() error {
+	// NOTE: called with mountainCertRotateMutex held
+	pubKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	pub, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+	cert, err := mountainCertSigner.signKey(pub, time.Duration(mountainCertTTLSecs)*time.Second)
+	if err != nil {
+		return fmt.Errorf("unable to sign the Mountain console key: %s", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "console-node")
+	if err != nil {
+		return err
+	}
+
+	// write the pair into a new directory then point 'current' at it
+	// NOTE: existing console sessions are not affected, new connections made by
+	//  conmand pick up the new certificate without a restart
+	if err := os.MkdirAll(mountainCertDir, 0700); err != nil {
+		return err
+	}
+	gen := fmt.Sprintf("gen-%d", time.Now().UnixNano())
+	genDir := filepath.Join(mountainCertDir, gen)
+	if err := os.Mkdir(genDir, 0700); err != nil {
+		return err
+	}
+	keyFile := filepath.Join(genDir, mountainCertKeyName)
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0600); err != nil {
+		return err
+	}
+	link := filepath.Join(mountainCertDir, "current")
+	tmpLink := link + ".tmp"
+	os.Remove(tmpLink)
+	if err := os.Symlink(gen, tmpLink); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpLink, link); err != nil {
+		return err
+	}
+
+	// remove the older pairs, keeping the one just replaced for connections in progress
+	mountainCertMutex.Lock()
+	mountainCert = cert
+	mountainCertIssued = time.Now()
+	mountainCertMutex.Unlock()
+	pruneMountainCerts(gen)
+
+	expires := time.Unix(int64(cert.ValidBefore), 0)
+	log.Printf("New Mountain console certificate serial %d valid until %s", cert.Serial, expires.Format(time.RFC3339))
+	raiseEvent("", "mountain_cert_rotated", eventInfo,
+		fmt.Sprintf("new Mountain console certificate valid until %s", expires.Format(time.RFC3339)),
+		map[string]string{"serial": fmt.Sprintf("%d", cert.Serial), "key_id": cert.KeyId,
+			"fingerprint": ssh.FingerprintSHA256(pub)})
+	return nil
+}
+
+// Remove all but the newest two generations of certificates
+func pruneMountainCerts(current string) {
+	entries, err := os.ReadDir(mountainCertDir)
+	if err != nil {
+		return
+	}
+	var gens []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), "gen-") {
+			gens = append(gens, e.Name())
+		}
+	}
+	// names sort by creation time
+	for i := 0; i < len(gens)-2; i++ {
+		if gens[i] != current {
+			os.RemoveAll(filepath.Join(mountainCertDir, gens[i]))
+		}
+	}
+}
+
+// Keep the certificate fresh, rotating it before it expires
+func watchMountainCert() {
+	reported := ""
+	for {
+		wait := time.Minute
+		if err := ensureMountainCert(); err != nil {
+			log.Printf("Unable to get a Mountain console certificate: %s", err)
+			if err.Error() != reported {
+				raiseEvent("", "mountain_cert_failed", eventWarning,
+					fmt.Sprintf("unable to get a Mountain console certificate: %s", err), nil)
+				reported = err.Error()
+			}
+			if wait = vaultSession.retryDelay(); wait < 15*time.Second {
+				wait = 15 * time.Second
+			}
+		} else {
+			reported = ""
+		}
+		time.Sleep(wait)
+	}
+}
+
+// State of the certificate for the health endpoint
+func getMountainCertStatus() *MountainCertStatus {
+	if mountainAuthMode != "cert" {
+		return nil
+	}
+	mountainCertMutex.Lock()
+	defer mountainCertMutex.Unlock()
+	st := &MountainCertStatus{Signer: mountainCertSigner.Name(), LastError: mountainCertError}
+	if mountainCert != nil {
+		st.Serial = mountainCert.Serial
+		st.ValidBefore = time.Unix(int64(mountainCert.ValidBefore), 0).Format(time.RFC3339)
+	}
+	return st
+}
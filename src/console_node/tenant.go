@@ -0,0 +1,352 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the tenants the consoles belong to
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TenantService - interface for finding the tenants that own nodes
+type TenantService interface {
+	lookupTenants(xnames []string) (map[string]string, error)
+}
+
+// NewTenantService creates the tenant service selected by the environment
+func NewTenantService() TenantService {
+	switch strings.ToLower(os.Getenv("TENANT_API")) {
+	case "tapms":
+		url := os.Getenv("TENANT_API_URL")
+		if url == "" {
+			url = "http://cray-tapms/apis/tapms/v1"
+		}
+		log.Printf("Using TAPMS for console tenants at %s", url)
+		return &TapmsManager{tapmsAddrBase: url}
+	case "file":
+		fn := os.Getenv("TENANT_MAP_FILE")
+		log.Printf("Using %s for console tenants", fn)
+		return &TenantFileManager{fileName: fn}
+	}
+	log.Printf("No tenant service, tenants only come from console-data")
+	return nil
+}
+
+// TapmsManager - struct for managing tenant and partition service interactions
+type TapmsManager struct {
+	tapmsAddrBase string
+}
+
+// TapmsTenant represents the parts of a TAPMS tenant used here.
+type TapmsTenant struct {
+	Spec struct {
+		TenantName      string `json:"tenantname"`
+		TenantResources []struct {
+			Xnames []string `json:"xnames"`
+		} `json:"tenantresources"`
+	} `json:"spec"`
+}
+
+// Find the tenants of the nodes from TAPMS
+func (tm TapmsManager) lookupTenants(xnames []string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/tenants", tm.tapmsAddrBase)
+	rb, sc, err := getURL(url, nil)
+	if err != nil {
+		log.Printf("Error making GET to %s\n", url)
+		return nil, err
+	}
+	if sc != http.StatusOK {
+		return nil, fmt.Errorf("tenant lookup failed, sc=%d", sc)
+	}
+	var tenants []TapmsTenant
+	if err := json.Unmarshal(rb, &tenants); err != nil {
+		log.Printf("Error unmarshalling return data: %s\n", err)
+		return nil, err
+	}
+	all := make(map[string]string)
+	for _, t := range tenants {
+		for _, tr := range t.Spec.TenantResources {
+			for _, xn := range tr.Xnames {
+				all[xn] = t.Spec.TenantName
+			}
+		}
+	}
+	return pickTenants(all, xnames), nil
+}
+
+// TenantFileManager - tenants read from a local file of tenant names to xnames
+type TenantFileManager struct {
+	fileName string
+}
+
+// Find the tenants of the nodes from the file
+func (tf TenantFileManager) lookupTenants(xnames []string) (map[string]string, error) {
+	data, err := os.ReadFile(tf.fileName)
+	if err != nil {
+		return nil, err
+	}
+	var tenants map[string][]string
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, err
+	}
+	all := make(map[string]string)
+	for tenant, nodes := range tenants {
+		for _, xn := range nodes {
+			all[xn] = tenant
+		}
+	}
+	return pickTenants(all, xnames), nil
+}
+
+// Keep only the tenants of the requested nodes
+func pickTenants(all map[string]string, xnames []string) map[string]string {
+	found := make(map[string]string)
+	for _, xn := range xnames {
+		if t, ok := all[xn]; ok {
+			found[xn] = t
+		}
+	}
+	return found
+}
+
+// Service used to look up tenants, nil if there is none
+var tenantService TenantService = nil
+
+// Tenant names are used in paths so only allow safe characters
+var tenantNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Globals holding the tenant of each console
+var tenantMutex = &sync.Mutex{}
+var consoleTenants map[string]string = make(map[string]string) // [xname,tenant]
+var tenantLookups map[string]bool = make(map[string]bool)      // [xname,true] tenant comes from the tenant service
+var tenantsPending map[string]bool = make(map[string]bool)     // [xname,true] held back until the tenant is known
+
+// Fill in the tenants of newly acquired nodes
+func assignTenants(nodes []nodeConsoleInfo) {
+	// NOTE: in doGetNewNodes thread
+	var missing []string
+	for _, node := range nodes {
+		if node.Tenant == "" {
+			missing = append(missing, node.NodeName)
+		}
+	}
+	if len(missing) > 0 && tenantService != nil {
+		// NOTE: fail closed, a console with an unknown tenant must not be
+		//  logged in the shared directories so it is held back until the
+		//  lookup works again
+		found, err := tenantService.lookupTenants(missing)
+		if err != nil {
+			log.Printf("Unable to look up console tenants, holding back %d consoles: %s", len(missing), err)
+		}
+		setTenantLookups(missing, err != nil)
+		for i := range nodes {
+			if t, ok := found[nodes[i].NodeName]; ok && nodes[i].Tenant == "" {
+				nodes[i].Tenant = t
+			}
+		}
+	}
+	for i := range nodes {
+		nodes[i].Tenant = validTenant(nodes[i].NodeName, nodes[i].Tenant)
+		setConsoleTenant(nodes[i].NodeName, nodes[i].Tenant)
+	}
+}
+
+// Check a tenant name can be used in paths, empty if it can not
+func validTenant(xname, tenant string) string {
+	if tenant != "" && !tenantNameRe.MatchString(tenant) {
+		log.Printf("Invalid tenant name %q for %s, treating it as untenanted", tenant, xname)
+		return ""
+	}
+	return tenant
+}
+
+// Record the consoles whose tenants come from the tenant service, and if
+// they are held back because the lookup failed
+func setTenantLookups(xnames []string, pending bool) {
+	var held []string
+	tenantMutex.Lock()
+	for _, xname := range xnames {
+		tenantLookups[xname] = true
+		if !pending {
+			delete(tenantsPending, xname)
+		} else if !tenantsPending[xname] {
+			tenantsPending[xname] = true
+			held = append(held, xname)
+		}
+	}
+	tenantMutex.Unlock()
+
+	// NOTE: raise the events outside the tenant lock
+	for _, xname := range held {
+		raiseEvent(xname, "tenant_pending", eventWarning,
+			fmt.Sprintf("tenant lookup failed, console %s is not connected", xname), nil)
+	}
+}
+
+// Check if a console is held back until its tenant is known
+func tenantPending(xname string) bool {
+	tenantMutex.Lock()
+	defer tenantMutex.Unlock()
+	return tenantsPending[xname]
+}
+
+// Number of consoles held back until their tenant is known
+func numPendingTenants() int {
+	tenantMutex.Lock()
+	defer tenantMutex.Unlock()
+	return len(tenantsPending)
+}
+
+// Sorted names of the consoles held back until their tenant is known
+func pendingTenantConsoles() []string {
+	tenantMutex.Lock()
+	defer tenantMutex.Unlock()
+	var xnames []string
+	for xname := range tenantsPending {
+		xnames = append(xnames, xname)
+	}
+	sort.Strings(xnames)
+	return xnames
+}
+
+// Look the tenants of the current consoles up again, returns if conman
+// needs to be reconfigured for consoles that changed tenant or were held back
+func checkIfTenantsChanged() bool {
+	// NOTE: in monitor thread
+	if tenantService == nil {
+		return false
+	}
+
+	// forget the consoles no longer monitored by this pod
+	currNodesMutex.Lock()
+	tenantMutex.Lock()
+	for xname := range tenantLookups {
+		if currentConsole(xname) == nil {
+			delete(tenantLookups, xname)
+			delete(tenantsPending, xname)
+		}
+	}
+	var xnames []string
+	for xname := range tenantLookups {
+		xnames = append(xnames, xname)
+	}
+	tenantMutex.Unlock()
+	currNodesMutex.Unlock()
+	if len(xnames) == 0 {
+		return false
+	}
+
+	// look up outside the node lock
+	found, err := tenantService.lookupTenants(xnames)
+	if err != nil {
+		// NOTE: consoles already connected keep their tenant until the lookup works again
+		log.Printf("Unable to check the console tenants: %s", err)
+		return false
+	}
+
+	currNodesMutex.Lock()
+	defer currNodesMutex.Unlock()
+	changed := false
+	for _, xname := range xnames {
+		ni := currentConsole(xname)
+		if ni == nil {
+			continue
+		}
+		tenant := validTenant(xname, found[xname])
+		if tenantPending(xname) {
+			// the console was held back, so nothing was written for it yet
+			raiseEvent(xname, "tenant_found", eventInfo,
+				fmt.Sprintf("tenant found, adding console %s", xname), map[string]string{"tenant": tenant})
+			ni.Tenant = tenant
+			setConsoleTenant(xname, tenant)
+			changed = true
+		} else if tenant != ni.Tenant {
+			log.Printf("Console %s moved from tenant %q to %q", xname, ni.Tenant, tenant)
+			raiseEvent(xname, "tenant_changed", eventInfo,
+				fmt.Sprintf("console %s moved from tenant %q to %q", xname, ni.Tenant, tenant),
+				map[string]string{"from": ni.Tenant, "to": tenant})
+			// the files of the console are picked up again in the new tenant directories
+			stopTailing(xname)
+			ni.Tenant = tenant
+			setConsoleTenant(xname, tenant)
+			changed = true
+		}
+	}
+	setTenantLookups(xnames, false)
+	return changed
+}
+
+// Find a console monitored by this pod
+func currentConsole(xname string) *nodeConsoleInfo {
+	// NOTE: currNodesMutex must be held by the caller
+	allNodes := [3](*map[string]*nodeConsoleInfo){&currentRvrNodes, &currentPdsNodes, &currentMtnNodes}
+	for _, ar := range allNodes {
+		if ni, ok := (*ar)[xname]; ok {
+			return ni
+		}
+	}
+	return nil
+}
+
+// Record the tenant of a console and make its directories
+func setConsoleTenant(xname, tenant string) {
+	tenantMutex.Lock()
+	if tenant == "" {
+		delete(consoleTenants, xname)
+	} else {
+		consoleTenants[xname] = tenant
+	}
+	tenantMutex.Unlock()
+
+	if tenant != "" {
+		for _, base := range []string{consoleLogDir, logRotDir, consoleIndexDir, consoleIndexOldDir, recordingDir, recordingOldDir} {
+			ensureDirPresent(tenantDir(base, xname), logDirMode)
+		}
+	}
+}
+
+// Get the tenant of a console, empty if it has none
+func consoleTenant(xname string) string {
+	tenantMutex.Lock()
+	defer tenantMutex.Unlock()
+	return consoleTenants[xname]
+}
+
+// Directory holding the files of a console under a base directory
+func tenantDir(base, xname string) string {
+	if tenant := consoleTenant(xname); tenant != "" {
+		return filepath.Join(base, "tenants", tenant)
+	}
+	return base
+}
@@ -0,0 +1,499 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the sidecar time indexes that map the time output was
+// received to the offset of that output in the console log files.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Location of the time indexes of the console log files
+// NOTE: rotated indexes are numbered to match the rotated log files
+const consoleIndexDir string = "/var/log/conman/index"
+const consoleIndexOldDir string = "/var/log/conman.old/index"
+
+// Minimum time between entries in a time index
+var timeIndexIntervalMs int = 1000
+
+// Size of a single index entry on disk - int64 unix nanoseconds, int64 offset
+const timeIndexEntrySize int = 16
+
+// A single entry in a time index
+type timeIndexEntry struct {
+	when   time.Time
+	offset int64
+}
+
+// An open time index that is being added to
+type timeIndex struct {
+	path      string
+	file      *os.File
+	lastEntry time.Time
+}
+
+// Read the time index configuration from the environment
+func initTimeIndexes() {
+	readSingleEnvVarInt("CONSOLE_INDEX_INTERVAL_MS", &timeIndexIntervalMs, 10, 60000)
+	log.Printf("Console time index interval ms: %d", timeIndexIntervalMs)
+	ensureDirPresent(consoleIndexDir, logDirMode)
+	ensureDirPresent(consoleIndexOldDir, logDirMode)
+}
+
+// Name of the time index for the current console log file
+func consoleIndexFile(xname string) string {
+	return filepath.Join(tenantDir(consoleIndexDir, xname), fmt.Sprintf("console.%s.tidx", xname))
+}
+
+// Name of the time index for a rotated console log file
+func rotatedConsoleIndexFile(xname string, num int) string {
+	return filepath.Join(tenantDir(consoleIndexOldDir, xname), fmt.Sprintf("console.%s.tidx.%d", xname, num))
+}
+
+// Name of the markers added to the current console log file
+func consoleMarkFile(xname string) string {
+	return filepath.Join(tenantDir(consoleIndexDir, xname), fmt.Sprintf("console.%s.marks", xname))
+}
+
+// Name of the markers added to a rotated console log file
+func rotatedConsoleMarkFile(xname string, num int) string {
+	return filepath.Join(tenantDir(consoleIndexOldDir, xname), fmt.Sprintf("console.%s.marks.%d", xname, num))
+}
+
+// Name of a rotated console log file
+func rotatedConsoleLogFile(xname string, num int) string {
+	return filepath.Join(tenantDir(logRotDir, xname), fmt.Sprintf("console.%s.%d", xname, num))
+}
+
+// Open a time index for appending new entries
+func openTimeIndex(path string) *timeIndex {
+	ti := &timeIndex{path: path}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, logFileMode)
+	if err != nil {
+		log.Printf("Unable to open time index %s: %s", path, err)
+		return ti
+	}
+	ti.file = f
+	return ti
+}
+
+// Record the offset of output received at the given time
+func (ti *timeIndex) add(when time.Time, offset int64) {
+	// NOTE: entries are only added at the index interval to keep the file compact
+	if ti.file == nil || when.Sub(ti.lastEntry) < time.Duration(timeIndexIntervalMs)*time.Millisecond {
+		return
+	}
+	var buf [timeIndexEntrySize]byte
+	binary.BigEndian.PutUint64(buf[0:8], uint64(when.UnixNano()))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(offset))
+	if _, err := ti.file.Write(buf[:]); err != nil {
+		log.Printf("Error writing time index %s: %s", ti.path, err)
+		return
+	}
+	ti.lastEntry = when
+}
+
+// Close the time index
+func (ti *timeIndex) close() {
+	if ti.file != nil {
+		ti.file.Close()
+		ti.file = nil
+	}
+}
+
+// Move the rotated index files back one and start a new index
+func (ti *timeIndex) rotate(rotatedName func(num int) string) {
+	ti.close()
+	shiftRotatedFiles(ti.path, rotatedName, logRotConNumRotate)
+	*ti = *openTimeIndex(ti.path)
+}
+
+// Shift the rotated copies of a file back one and move the current file to the first copy
+func shiftRotatedFiles(current string, rotatedName func(num int) string, numKeep int) {
+	if numKeep < 1 {
+		numKeep = 1
+	}
+	os.Remove(rotatedName(numKeep))
+	for i := numKeep - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedName(i), rotatedName(i+1)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error rotating %s: %s", rotatedName(i), err)
+		}
+	}
+	if err := os.Rename(current, rotatedName(1)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error rotating %s: %s", current, err)
+	}
+}
+
+// Read all the entries of a time index
+func readTimeIndex(path string) ([]timeIndexEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]timeIndexEntry, 0, len(data)/timeIndexEntrySize)
+	for pos := 0; pos+timeIndexEntrySize <= len(data); pos += timeIndexEntrySize {
+		entries = append(entries, timeIndexEntry{
+			when:   time.Unix(0, int64(binary.BigEndian.Uint64(data[pos:pos+8]))),
+			offset: int64(binary.BigEndian.Uint64(data[pos+8 : pos+16])),
+		})
+	}
+	return entries, nil
+}
+
+// Find the offset to start reading from to get output received at or after a time
+func indexStartOffset(entries []timeIndexEntry, since time.Time) int64 {
+	// find the first entry after the requested time then back up one
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].when.After(since) })
+	if i == 0 {
+		return 0
+	}
+	return entries[i-1].offset
+}
+
+// Find the offset to stop reading at to get output received at or before a time
+func indexEndOffset(entries []timeIndexEntry, until time.Time) int64 {
+	// the first entry after the requested time marks the end, -1 means read to the end
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].when.After(until) })
+	if i == len(entries) {
+		return -1
+	}
+	return entries[i].offset
+}
+
+// Find the range of times the output at an offset may have been received in,
+// the latest time is exclusive and zero when it is not known
+func indexTimeRange(entries []timeIndexEntry, offset int64) (time.Time, time.Time) {
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].offset > offset })
+	if i == 0 {
+		return time.Time{}, time.Time{}
+	}
+	// NOTE: output is only left out of the index when it came within one
+	//  interval of the entry before it
+	earliest := entries[i-1].when
+	if entries[i-1].offset == offset {
+		return earliest, earliest.Add(time.Nanosecond)
+	}
+	latest := earliest.Add(time.Duration(timeIndexIntervalMs) * time.Millisecond)
+	if i < len(entries) && entries[i].when.Before(latest) {
+		latest = entries[i].when
+	}
+	return earliest, latest
+}
+
+// Follows the file being tailed to notice when it is rotated or truncated
+type tailFileTracker struct {
+	path string
+	dev  uint64
+	ino  uint64
+}
+
+// Start following the file currently at a path
+func newTailFileTracker(path string) *tailFileTracker {
+	t := &tailFileTracker{path: path}
+	t.dev, t.ino, _ = fileIdentity(path)
+	return t
+}
+
+// Get the device and inode of a file, and its size
+func fileIdentity(path string) (uint64, uint64, int64) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, -1
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fi.Size()
+	}
+	return uint64(st.Dev), st.Ino, fi.Size()
+}
+
+// Check if the file at the path has been replaced, or cut shorter than the
+// offset already read, since it was last checked
+func (t *tailFileTracker) changed(offset int64) bool {
+	dev, ino, size := fileIdentity(t.path)
+	if size < 0 {
+		// moved away and not yet replaced, the tail still reads the old file
+		return false
+	}
+	if dev != t.dev || ino != t.ino {
+		t.dev, t.ino = dev, ino
+		return true
+	}
+	return size < offset
+}
+
+// A console log file along with its time index
+type indexedLogFile struct {
+	logFile   string
+	indexFile string
+	markFile  string // markers added to the log, empty if there are none
+}
+
+// A marker console-node added to a console log, kept next to the time index
+// NOTE: conmand owns the console log, so the marker is not written into it but
+//
+//	merged with the log lines at its offset when the log is read
+type consoleMark struct {
+	Time   time.Time `json:"time"`
+	Offset int64     `json:"offset"`
+	Text   string    `json:"text"`
+}
+
+// Record a marker at the current end of the console log of a console
+func addConsoleMark(xname, text string, when time.Time) {
+	var offset int64 = 0
+	if fi, err := os.Stat(consoleLogFile(xname)); err == nil {
+		offset = fi.Size()
+	}
+	data, err := json.Marshal(consoleMark{Time: when, Offset: offset, Text: text})
+	if err != nil {
+		return
+	}
+	path := consoleMarkFile(xname)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, logFileMode)
+	if err != nil {
+		log.Printf("Unable to open console markers %s: %s", path, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Error writing console markers %s: %s", path, err)
+	}
+}
+
+// Read the markers added to a console log
+func readConsoleMarks(path string) []consoleMark {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var marks []consoleMark
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var m consoleMark
+		if json.Unmarshal(scanner.Bytes(), &m) == nil {
+			marks = append(marks, m)
+		}
+	}
+	return marks
+}
+
+// All the console log files for a console from oldest to newest
+func consoleLogFiles(xname string) []indexedLogFile {
+	var files []indexedLogFile
+	for i := logRotConNumRotate; i >= 1; i-- {
+		fn := rotatedConsoleLogFile(xname, i)
+		if _, err := os.Stat(fn); err == nil {
+			files = append(files, indexedLogFile{logFile: fn, indexFile: rotatedConsoleIndexFile(xname, i),
+				markFile: rotatedConsoleMarkFile(xname, i)})
+		}
+	}
+	files = append(files, indexedLogFile{logFile: consoleLogFile(xname), indexFile: consoleIndexFile(xname),
+		markFile: consoleMarkFile(xname)})
+	return files
+}
+
+// ConsoleLogLine - a single line of console log output
+type ConsoleLogLine struct {
+	Time   string `json:"time,omitempty"`
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`
+}
+
+// Read the lines of the console log received inside a time range
+func readConsoleLogRange(xname string, since, until time.Time, handler func(ll ConsoleLogLine, when time.Time) bool) {
+	for _, lf := range consoleLogFiles(xname) {
+		entries, err := readTimeIndex(lf.indexFile)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Unable to read time index %s: %s", lf.indexFile, err)
+		}
+
+		// without an index the whole file is read and filtered by the conman timestamps
+		start := int64(0)
+		end := int64(-1)
+		if len(entries) > 0 {
+			// skip files entirely outside of the time range
+			if !since.IsZero() && lf.logFile != consoleLogFile(xname) {
+				if fi, err := os.Stat(lf.logFile); err == nil && fi.ModTime().Before(since) {
+					continue
+				}
+			}
+			if !until.IsZero() && entries[0].when.After(until) {
+				return
+			}
+			if !since.IsZero() {
+				start = indexStartOffset(entries, since)
+			}
+			if !until.IsZero() {
+				end = indexEndOffset(entries, until)
+			}
+		}
+
+		f, err := os.Open(lf.logFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Unable to open %s: %s", lf.logFile, err)
+			}
+			continue
+		}
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			log.Printf("Unable to seek in %s: %s", lf.logFile, err)
+			f.Close()
+			continue
+		}
+
+		// the markers go in ahead of the first line at or past their offset
+		marks := readConsoleMarks(lf.markFile)
+		emitMarks := func(before int64) bool {
+			for len(marks) > 0 && (before < 0 || marks[0].Offset <= before) {
+				m := marks[0]
+				marks = marks[1:]
+				if m.Offset < start || (!since.IsZero() && m.Time.Before(since)) || (!until.IsZero() && m.Time.After(until)) {
+					continue
+				}
+				ll := ConsoleLogLine{Time: m.Time.Format(time.RFC3339Nano), File: lf.logFile, Offset: m.Offset, Text: m.Text}
+				if !handler(ll, m.Time) {
+					return false
+				}
+			}
+			return true
+		}
+
+		rd := bufio.NewReader(f)
+		offset := start
+		var markTime time.Time
+		for end < 0 || offset < end {
+			line, n, err := readLogLine(rd)
+			if n > 0 {
+				ll := ConsoleLogLine{File: lf.logFile, Offset: offset, Text: strings.TrimRight(line, "\n")}
+				offset += n
+				if !emitMarks(ll.Offset) {
+					f.Close()
+					return
+				}
+
+				// NOTE: the index only brackets the time of the lines at its ends,
+				//  so trim those by the times conman writes in the log
+				when, latest := indexTimeRange(entries, ll.Offset)
+				if ts, _ := splitConmanTimestamp(ll.Text); !ts.IsZero() {
+					// conman writes whole seconds
+					when, latest = ts, ts.Add(time.Second)
+				} else if ts := conmanMarkTime(ll.Text); !ts.IsZero() {
+					markTime = ts
+				}
+				if when.IsZero() {
+					when = markTime
+				}
+				if !until.IsZero() && !when.IsZero() && when.After(until) {
+					f.Close()
+					return
+				}
+				if !since.IsZero() && !latest.IsZero() && !latest.After(since) {
+					continue
+				}
+
+				if !when.IsZero() {
+					ll.Time = when.Format(time.RFC3339Nano)
+				}
+				if !handler(ll, when) {
+					f.Close()
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		f.Close()
+		if !emitMarks(-1) {
+			return
+		}
+	}
+}
+
+// Handle the request for the console log over a time range
+func doConsoleLog(w http.ResponseWriter, r *http.Request, xname string) {
+	// only allow 'GET' calls
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("(%s) Not Allowed", r.Method))
+		return
+	}
+
+	// pull out the time range
+	q := r.URL.Query()
+	since, err := parseTimeParam(q.Get("since"))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid since: %s", err))
+		return
+	}
+	until, err := parseTimeParam(q.Get("until"))
+	if err != nil {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid until: %s", err))
+		return
+	}
+	format := q.Get("format")
+	if format != "" && format != "text" && format != "json" {
+		sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown format: %s", format))
+		return
+	}
+
+	// stream out the lines as they are read
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	readConsoleLogRange(xname, since, until, func(ll ConsoleLogLine, when time.Time) bool {
+		var err error
+		if format == "json" {
+			err = enc.Encode(ll)
+		} else {
+			_, err = io.WriteString(w, ll.Text+"\n")
+		}
+		return err == nil && r.Context().Err() == nil
+	})
+}
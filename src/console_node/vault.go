@@ -0,0 +1,452 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the shared session with vault
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Limits of the wait between failed attempts to log in to vault
+const vaultMinBackoff time.Duration = 2 * time.Second
+const vaultMaxBackoff time.Duration = 5 * time.Minute
+
+// Time allowed for a single request to vault
+var vaultTimeoutSecs int = 10
+
+// VaultAuthStatus - state of the vault login reported in the health endpoint
+type VaultAuthStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	TokenExpires  string `json:"token_expires,omitempty"`
+	LastLogin     string `json:"last_login,omitempty"`
+	LastRenewal   string `json:"last_renewal,omitempty"`
+	Failures      int    `json:"consecutive_failures"`
+	LastError     string `json:"last_error,omitempty"`
+	RetryAt       string `json:"retry_at,omitempty"`
+}
+
+// VaultSession - a vault token shared by everything that talks to vault
+type VaultSession struct {
+	mu          sync.Mutex
+	baseURL     string
+	namespace   string
+	auth        VaultAuthMethod
+	client      *http.Client
+	token       string
+	ttl         time.Duration // zero if the token does not expire
+	expires     time.Time
+	renewable   bool
+	lastLogin   time.Time
+	lastRenewal time.Time
+	failures    int
+	lastError   string
+	retryAt     time.Time
+	refreshing  chan struct{} // closed when the login or renewal in progress is done
+}
+
+// Session used for all vault requests
+var vaultSession *VaultSession = nil
+
+// NewVaultSession creates the vault session for the Mountain console key and certificates
+func NewVaultSession() *VaultSession {
+	return newVaultSession("ssh keys", NewVaultAuthMethod(os.Getenv("VAULT_AUTH_ROLE"), ""))
+}
+
+// NewCredsVaultSession creates the vault session for the BMC credentials
+// NOTE: the role of the credentials is not the one of the ssh keys, as with
+// hms-securestorage it defaults to the namespace of the pod
+func NewCredsVaultSession() *VaultSession {
+	if simulationMode() {
+		return vaultSession
+	}
+	roleFile := os.Getenv("CREDS_VAULT_ROLE_FILE")
+	if roleFile == "" {
+		roleFile = svcAcctNamespaceFile
+	}
+	return newVaultSession("BMC credentials", NewVaultAuthMethod(os.Getenv("CREDS_VAULT_ROLE"), roleFile))
+}
+
+// Create a vault session using the auth method
+func newVaultSession(use string, auth VaultAuthMethod) *VaultSession {
+	vaultBase := os.Getenv("VAULT_URL")
+	if len(vaultBase) == 0 {
+		log.Printf("VAULT_URL environment variable is not set, defaulting to http://cray-vault.vault:8200/v1")
+		vaultBase = "http://cray-vault.vault:8200/v1"
+	}
+	readSingleEnvVarInt("VAULT_TIMEOUT_SEC", &vaultTimeoutSecs, 1, 300)
+	vs := &VaultSession{
+		baseURL:   strings.TrimSuffix(vaultBase, "/"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		auth:      auth,
+		client:    &http.Client{Timeout: time.Duration(vaultTimeoutSecs) * time.Second},
+	}
+	tlsConfig, err := vaultTLSConfig()
+	if err != nil {
+		log.Printf("Unable to set up TLS for vault: %s", err)
+		vs.lastError = err.Error()
+	} else if tlsConfig != nil {
+		vs.client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	}
+	log.Printf("Using vault at %s with %s authentication for %s", vs.baseURL, vs.auth.Name(), use)
+	return vs
+}
+
+// TLS settings for talking to vault, nil to use the defaults
+func vaultTLSConfig() (*tls.Config, error) {
+	caFile := os.Getenv("VAULT_CACERT")
+	certFile := os.Getenv("VAULT_CLIENT_CERT")
+	keyFile := os.Getenv("VAULT_CLIENT_KEY")
+	if caFile == "" && certFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" {
+		// reload the client certificate on each handshake so it can be rotated
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		}
+	}
+	return tlsConfig, nil
+}
+
+// Send a request to vault
+// NOTE: the path is relative to the vault base url, ie /transit/export/...
+func (vs *VaultSession) do(method, path string, body []byte, token string) ([]byte, int, error) {
+	var reader io.Reader = nil
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, vs.baseURL+path, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if vs.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vs.namespace)
+	}
+	resp, err := vs.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	return response, resp.StatusCode, err
+}
+
+// Time a failed login waits before the next attempt, doubling up to a limit
+// with jitter so a restarted vault is not hit by every pod at once
+func vaultBackoff(failures int) time.Duration {
+	d := vaultMinBackoff
+	for i := 1; i < failures && d < vaultMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > vaultMaxBackoff {
+		d = vaultMaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Time to wait before trying vault again
+func (vs *VaultSession) retryDelay() time.Duration {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if wait := time.Until(vs.retryAt); wait > 0 {
+		return wait
+	}
+	return vaultBackoff(vs.failures)
+}
+
+// Record a failure talking to vault and when to try again
+func (vs *VaultSession) failed(err error) error {
+	// NOTE: called with the lock held
+	vs.failures++
+	vs.lastError = err.Error()
+	vs.retryAt = time.Now().Add(vaultBackoff(vs.failures))
+	log.Printf("Vault authentication failed (%d in a row), next attempt at %s: %s",
+		vs.failures, vs.retryAt.Format(time.RFC3339), err)
+	return err
+}
+
+// Take the token out of a login or renewal response
+func parseVaultAuth(response []byte) (string, time.Duration, bool, error) {
+	auth := gjson.GetBytes(response, "auth")
+	token := auth.Get("client_token").String()
+	if token == "" {
+		return "", 0, false, fmt.Errorf("no client token in the vault response")
+	}
+	return token, time.Duration(auth.Get("lease_duration").Int()) * time.Second, auth.Get("renewable").Bool(), nil
+}
+
+// Start using a new or renewed token
+func (vs *VaultSession) setToken(token string, ttl time.Duration, renewable bool) {
+	// NOTE: called with the lock held
+	vs.token = token
+	vs.ttl = ttl
+	vs.expires = time.Time{}
+	if vs.ttl > 0 {
+		vs.expires = time.Now().Add(vs.ttl)
+	}
+	vs.renewable = renewable
+	vs.failures = 0
+	vs.lastError = ""
+	vs.retryAt = time.Time{}
+}
+
+// Extend the lease of a token
+func (vs *VaultSession) renew(token string) (string, time.Duration, bool, error) {
+	response, sc, err := vs.do(http.MethodPost, "/auth/token/renew-self", []byte("{}"), token)
+	if err != nil {
+		return "", 0, false, err
+	}
+	if sc != http.StatusOK {
+		return "", 0, false, fmt.Errorf("vault token renewal failed, sc=%d", sc)
+	}
+	return parseVaultAuth(response)
+}
+
+// Get a valid token, logging in or renewing only when needed
+// NOTE: this never waits on the backoff, it fails right away until the next
+// attempt is due so callers holding other locks are not held up. Only one
+// caller talks to vault for a new token, without holding the session lock.
+func (vs *VaultSession) getToken() (string, error) {
+	vs.mu.Lock()
+
+	// use the current token until the last third of its lifetime
+	now := time.Now()
+	if vs.token != "" && (vs.expires.IsZero() || vs.expires.Sub(now) > vs.ttl/3) {
+		defer vs.mu.Unlock()
+		return vs.token, nil
+	}
+	stillValid := vs.token != "" && now.Before(vs.expires)
+	if now.Before(vs.retryAt) {
+		defer vs.mu.Unlock()
+		if stillValid {
+			return vs.token, nil
+		}
+		return "", fmt.Errorf("vault unavailable until %s: %s", vs.retryAt.Format(time.RFC3339), vs.lastError)
+	}
+
+	// someone else is already getting a new token
+	if vs.refreshing != nil {
+		if stillValid {
+			defer vs.mu.Unlock()
+			return vs.token, nil
+		}
+		wait := vs.refreshing
+		vs.mu.Unlock()
+		<-wait
+		vs.mu.Lock()
+		defer vs.mu.Unlock()
+		if vs.token == "" {
+			return "", fmt.Errorf("vault authentication failed: %s", vs.lastError)
+		}
+		return vs.token, nil
+	}
+	done := make(chan struct{})
+	vs.refreshing = done
+	oldToken := vs.token
+	renewable := vs.renewable
+	vs.mu.Unlock()
+
+	// renew a token that is still good, otherwise log in again
+	var token string
+	var ttl time.Duration
+	var err error
+	renewed := false
+	if stillValid && renewable {
+		token, ttl, renewable, err = vs.renew(oldToken)
+		if err == nil {
+			renewed = true
+		} else {
+			log.Printf("Unable to renew the vault token, logging in again: %s", err)
+		}
+	}
+	if !renewed {
+		log.Printf("Attempting to authenticate to Vault with %s", vs.auth.Name())
+		token, ttl, renewable, err = vs.auth.login(vs)
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.refreshing = nil
+	close(done)
+	if err != nil {
+		// keep using the current token until it runs out
+		vs.failed(err)
+		if stillValid && vs.token == oldToken {
+			return vs.token, nil
+		}
+		vs.token = ""
+		return "", err
+	}
+	vs.setToken(token, ttl, renewable)
+	if renewed {
+		vs.lastRenewal = time.Now()
+		log.Printf("Vault token renewed, valid for %s", vs.ttl)
+	} else {
+		vs.lastLogin = time.Now()
+		log.Printf("Vault authentication was successful, token valid for %s", vs.ttl)
+	}
+	return vs.token, nil
+}
+
+// Drop a token vault no longer accepts
+func (vs *VaultSession) invalidate(token string) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if vs.token == token {
+		vs.token = ""
+	}
+}
+
+// Make a request to vault with the session token
+func (vs *VaultSession) request(method, path string, body []byte) ([]byte, int, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		token, err := vs.getToken()
+		if err != nil {
+			return nil, 0, err
+		}
+		response, sc, err := vs.do(method, path, body, token)
+		if err == nil && sc == http.StatusForbidden && attempt == 0 {
+			// the token was revoked or expired early, log in again
+			log.Printf("Vault rejected the session token, logging in again")
+			vs.invalidate(token)
+			continue
+		}
+		return response, sc, err
+	}
+	return nil, http.StatusForbidden, fmt.Errorf("vault rejected a new token")
+}
+
+// State of the vault login
+func (vs *VaultSession) status() VaultAuthStatus {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	st := VaultAuthStatus{
+		Authenticated: vs.token != "" && (vs.expires.IsZero() || time.Now().Before(vs.expires)),
+		Failures:      vs.failures,
+		LastError:     vs.lastError,
+	}
+	if !vs.expires.IsZero() {
+		st.TokenExpires = vs.expires.Format(time.RFC3339)
+	}
+	if !vs.lastLogin.IsZero() {
+		st.LastLogin = vs.lastLogin.Format(time.RFC3339)
+	}
+	if !vs.lastRenewal.IsZero() {
+		st.LastRenewal = vs.lastRenewal.Format(time.RFC3339)
+	}
+	if !vs.retryAt.IsZero() {
+		st.RetryAt = vs.retryAt.Format(time.RFC3339)
+	}
+	return st
+}
+
+// VaultSecretStore - read only secure storage for compcreds using a session
+type VaultSecretStore struct {
+	session  *VaultSession
+	basePath string
+}
+
+// Read a secret into the output struct
+func (vss *VaultSecretStore) Lookup(key string, output interface{}) error {
+	response, sc, err := vss.session.request(http.MethodGet, "/"+vss.basePath+"/"+key, nil)
+	if err != nil {
+		return err
+	}
+	if sc == http.StatusNotFound {
+		return errCredsNotFound
+	}
+	if sc != http.StatusOK {
+		return fmt.Errorf("vault lookup of %s failed, sc=%d", key, sc)
+	}
+	return json.Unmarshal([]byte(gjson.GetBytes(response, "data").Raw), output)
+}
+
+// List the keys under a path
+func (vss *VaultSecretStore) LookupKeys(keyPath string) ([]string, error) {
+	response, sc, err := vss.session.request(http.MethodGet, "/"+vss.basePath+"/"+keyPath+"?list=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	if sc != http.StatusOK {
+		return nil, fmt.Errorf("vault list of %s failed, sc=%d", keyPath, sc)
+	}
+	var keys []string
+	for _, k := range gjson.GetBytes(response, "data.keys").Array() {
+		keys = append(keys, k.String())
+	}
+	return keys, nil
+}
+
+// Store is not needed by console-node
+func (vss *VaultSecretStore) Store(key string, value interface{}) error {
+	return fmt.Errorf("vault secret store is read only")
+}
+
+// StoreWithData is not needed by console-node
+func (vss *VaultSecretStore) StoreWithData(key string, value interface{}, output interface{}) error {
+	return fmt.Errorf("vault secret store is read only")
+}
+
+// Delete is not needed by console-node
+func (vss *VaultSecretStore) Delete(key string) error {
+	return fmt.Errorf("vault secret store is read only")
+}
@@ -0,0 +1,225 @@
+//
+//  MIT License
+//
+//  (C) Copyright 2026 Hewlett Packard Enterprise Development LP
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a
+//  copy of this software and associated documentation files (the "Software"),
+//  to deal in the Software without restriction, including without limitation
+//  the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the
+//  Software is furnished to do so, subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included
+//  in all copies or substantial portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+//  OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+//  ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+//  OTHER DEALINGS IN THE SOFTWARE.
+//
+
+// This file contains the ways of logging in to vault
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// VaultAuthMethod - interface for logging in to vault
+type VaultAuthMethod interface {
+	login(vs *VaultSession) (token string, ttl time.Duration, renewable bool, err error)
+	Name() string
+}
+
+// NewVaultAuthMethod creates the vault auth method selected by the environment
+// NOTE: the role is read from roleFile at each login when it is not given
+func NewVaultAuthMethod(role, roleFile string) VaultAuthMethod {
+	method := strings.ToLower(os.Getenv("VAULT_AUTH_METHOD"))
+	if method == "" {
+		method = "kubernetes"
+	}
+	mount := strings.Trim(os.Getenv("VAULT_AUTH_PATH"), "/")
+	if mount == "" {
+		mount = method
+	}
+	switch method {
+	case "approle":
+		return &VaultAppRoleAuth{
+			mount:        mount,
+			roleID:       os.Getenv("VAULT_ROLE_ID"),
+			roleIDFile:   os.Getenv("VAULT_ROLE_ID_FILE"),
+			secretIDFile: os.Getenv("VAULT_SECRET_ID_FILE"),
+		}
+	case "token":
+		fn := os.Getenv("VAULT_TOKEN_FILE")
+		if fn == "" {
+			fn = "/var/run/secrets/vault/token"
+		}
+		return &VaultTokenFileAuth{fileName: fn}
+	case "cert":
+		return &VaultCertAuth{mount: mount, role: role}
+	case "kubernetes":
+	default:
+		log.Printf("Unknown VAULT_AUTH_METHOD %s, using kubernetes", method)
+		mount = "kubernetes"
+	}
+	if role == "" && roleFile == "" {
+		role = "ssh-user-certs-compute"
+	}
+	jwtFile := os.Getenv("VAULT_JWT_FILE")
+	if jwtFile == "" {
+		jwtFile = svcAcctTokenFile
+	}
+	return &VaultKubernetesAuth{mount: mount, role: role, roleFile: roleFile, jwtFile: jwtFile}
+}
+
+// Post a login request and take the token out of the response
+func vaultLogin(vs *VaultSession, mount string, params map[string]string) (string, time.Duration, bool, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return "", 0, false, err
+	}
+	response, sc, err := vs.do(http.MethodPost, "/auth/"+mount+"/login", body, "")
+	if err != nil {
+		return "", 0, false, err
+	}
+	if sc != http.StatusOK {
+		return "", 0, false, fmt.Errorf("vault login to auth/%s failed, sc=%d", mount, sc)
+	}
+	return parseVaultAuth(response)
+}
+
+// Read a secret from a file, dropping the trailing newline
+func readSecretFile(fn string) (string, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// VaultKubernetesAuth - log in with the kubernetes service account token
+type VaultKubernetesAuth struct {
+	mount    string
+	role     string
+	roleFile string
+	jwtFile  string
+}
+
+// Name of the auth method
+func (ka *VaultKubernetesAuth) Name() string {
+	return "kubernetes"
+}
+
+// Log in with the service account token
+func (ka *VaultKubernetesAuth) login(vs *VaultSession) (string, time.Duration, bool, error) {
+	// NOTE: the token is read each time since kubernetes rotates it
+	jwt, err := readSecretFile(ka.jwtFile)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("unable to read the service account token file: %s", err)
+	}
+	role := ka.role
+	if role == "" {
+		if role, err = readSecretFile(ka.roleFile); err != nil {
+			return "", 0, false, fmt.Errorf("unable to read the vault role file: %s", err)
+		}
+	}
+	return vaultLogin(vs, ka.mount, map[string]string{"jwt": jwt, "role": role})
+}
+
+// VaultAppRoleAuth - log in with an AppRole role id and secret id
+type VaultAppRoleAuth struct {
+	mount        string
+	roleID       string
+	roleIDFile   string
+	secretIDFile string
+}
+
+// Name of the auth method
+func (aa *VaultAppRoleAuth) Name() string {
+	return "approle"
+}
+
+// Log in with the role id and secret id
+func (aa *VaultAppRoleAuth) login(vs *VaultSession) (string, time.Duration, bool, error) {
+	roleID := aa.roleID
+	if aa.roleIDFile != "" {
+		var err error
+		if roleID, err = readSecretFile(aa.roleIDFile); err != nil {
+			return "", 0, false, fmt.Errorf("unable to read the AppRole role id: %s", err)
+		}
+	}
+	if roleID == "" {
+		return "", 0, false, fmt.Errorf("no AppRole role id set")
+	}
+	params := map[string]string{"role_id": roleID}
+	if aa.secretIDFile != "" {
+		secretID, err := readSecretFile(aa.secretIDFile)
+		if err != nil {
+			return "", 0, false, fmt.Errorf("unable to read the AppRole secret id: %s", err)
+		}
+		params["secret_id"] = secretID
+	}
+	return vaultLogin(vs, aa.mount, params)
+}
+
+// VaultTokenFileAuth - use a token written to a file by an agent or by hand
+type VaultTokenFileAuth struct {
+	fileName string
+}
+
+// Name of the auth method
+func (ta *VaultTokenFileAuth) Name() string {
+	return "token"
+}
+
+// Read the token and ask vault how long it is good for
+func (ta *VaultTokenFileAuth) login(vs *VaultSession) (string, time.Duration, bool, error) {
+	token, err := readSecretFile(ta.fileName)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("unable to read the vault token file: %s", err)
+	}
+	response, sc, err := vs.do(http.MethodGet, "/auth/token/lookup-self", nil, token)
+	if err != nil {
+		return "", 0, false, err
+	}
+	if sc != http.StatusOK {
+		return "", 0, false, fmt.Errorf("vault token lookup failed, sc=%d", sc)
+	}
+	data := gjson.GetBytes(response, "data")
+	return token, time.Duration(data.Get("ttl").Int()) * time.Second, data.Get("renewable").Bool(), nil
+}
+
+// VaultCertAuth - log in with the TLS client certificate
+// NOTE: the certificate is set with VAULT_CLIENT_CERT and VAULT_CLIENT_KEY
+type VaultCertAuth struct {
+	mount string
+	role  string
+}
+
+// Name of the auth method
+func (ca *VaultCertAuth) Name() string {
+	return "cert"
+}
+
+// Log in with the client certificate presented during the TLS handshake
+func (ca *VaultCertAuth) login(vs *VaultSession) (string, time.Duration, bool, error) {
+	params := map[string]string{}
+	if ca.role != "" {
+		params["name"] = ca.role
+	}
+	return vaultLogin(vs, ca.mount, params)
+}